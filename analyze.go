@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// runAnalyze implements "flagrep analyze FILE...".
+func runAnalyze(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOut := fs.Bool("json", false, "Print one JSON object per file")
+	blockSize := fs.Int("block", 0, "Entropy curve block size in bytes (default: about 64 blocks)")
+	top := fs.Int("ngrams", 5, "Number of repeated 4- and 8-grams to show")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep analyze [-json] [-block N] [-ngrams N] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	enc := json.NewEncoder(stdout)
+	for i, path := range fs.Args() {
+		content, err := os.ReadFile(path)
+		if err == nil && len(content) == 0 {
+			err = errors.New("empty file")
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		a := flagrep.Analyze(path, content, *blockSize, *top)
+		if *jsonOut {
+			enc.Encode(a)
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprint(stdout, flagrep.FormatAnalysis(a))
+	}
+	return status
+}
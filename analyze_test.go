@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+func TestRunAnalyze(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if status := runAnalyze([]string{"-json", exe}, &stdout, &stderr); status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	var a flagrep.Analysis
+	if err := json.Unmarshal(stdout.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Header == nil || a.Magic == "application/octet-stream" {
+		t.Errorf("executable not recognized: magic %q, header %v", a.Magic, a.Header)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, nil, 0644)
+	stdout.Reset()
+	if status := runAnalyze([]string{empty}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1 for an empty file, got %d", status)
+	}
+}
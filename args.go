@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// normalizeArgs rewrites GNU-style command lines into what the flag package
+// parses: grouped short flags like -ri or -rA5 are split, and flags after
+// positional arguments are moved in front of them. Everything after "--"
+// stays positional, and "--" is added so that a pattern starting with a
+// dash is not taken for a flag.
+func normalizeArgs(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		needsValue := false
+		if f := fs.Lookup(name); f != nil {
+			flags = append(flags, arg)
+			needsValue = !hasValue && !isBoolFlag(f)
+		} else if group, ok := splitGroup(fs, arg); ok {
+			flags = append(flags, group...)
+			last := fs.Lookup(strings.TrimPrefix(group[len(group)-1], "-"))
+			needsValue = last != nil && !isBoolFlag(last)
+		} else {
+			// unknown, let the flag package report it
+			flags = append(flags, arg)
+		}
+		if needsValue && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return append(append(flags, "--"), positional...)
+}
+
+// splitGroup splits -ri into -r -i. A flag taking a value ends the group,
+// with the rest of the group as its value: -rA5 is -r -A=5.
+func splitGroup(fs *flag.FlagSet, arg string) ([]string, bool) {
+	if strings.HasPrefix(arg, "--") {
+		return nil, false
+	}
+	var group []string
+	for i := 1; i < len(arg); i++ {
+		f := fs.Lookup(arg[i : i+1])
+		if f == nil {
+			return nil, false
+		}
+		if !isBoolFlag(f) {
+			if value := arg[i+1:]; value != "" {
+				return append(group, "-"+f.Name+"="+value), true
+			}
+			return append(group, "-"+f.Name), true
+		}
+		group = append(group, "-"+f.Name)
+	}
+	return group, true
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
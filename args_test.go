@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	recursive := fs.Bool("r", false, "")
+	fs.BoolVar(recursive, "recursive", false, "")
+	ignoreCase := fs.Bool("i", false, "")
+	fs.BoolVar(ignoreCase, "ignore-case", false, "")
+	after := fs.Int("A", 0, "")
+	fs.Bool("vv", false, "")
+	format := fs.String("format", "text", "")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"flag", "dir", "-r"}, []string{"-r", "--", "flag", "dir"}},
+		{[]string{"-ri", "flag"}, []string{"-r", "-i", "--", "flag"}},
+		{[]string{"-rA5", "flag"}, []string{"-r", "-A=5", "--", "flag"}},
+		{[]string{"-rA", "5", "flag"}, []string{"-r", "-A", "5", "--", "flag"}},
+		{[]string{"flag", "--format", "json", "."}, []string{"--format", "json", "--", "flag", "."}},
+		{[]string{"-vv", "flag"}, []string{"-vv", "--", "flag"}},
+		{[]string{"--ignore-case", "--", "-x", "-r"}, []string{"--ignore-case", "--", "-x", "-r"}},
+		{[]string{"flag", "-"}, []string{"--", "flag", "-"}},
+	}
+	for _, tt := range tests {
+		if got := normalizeArgs(fs, tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("normalizeArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, []string{"flag", ".", "--recursive", "-iA", "7", "--format=csv"})); err != nil {
+		t.Fatal(err)
+	}
+	if !*recursive || !*ignoreCase || *after != 7 || *format != "csv" || !slices.Equal(fs.Args(), []string{"flag", "."}) {
+		t.Errorf("parsed r=%v i=%v A=%d format=%s args=%q", *recursive, *ignoreCase, *after, *format, fs.Args())
+	}
+}
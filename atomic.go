@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is written under a temporary name next to its destination and
+// only renamed into place by Commit, so readers never see a partial file.
+// It keeps the 0600 mode of CreateTemp: results quote the secrets found.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+func createAtomic(path string) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+func (f *atomicFile) Commit() error {
+	if err := f.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), f.path)
+}
+
+func (f *atomicFile) Abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+const benchFlag = "flag{bench_mark}"
+
+// words the synthetic corpora are made of, so that text decoders and the
+// classifier see something like prose
+var benchWords = strings.Fields("the of and to in is was for on that with as by at from this be are or an " +
+	"key secret token password server config data value user file system error request response")
+
+type benchResult struct {
+	Encoding   string  `json:"encoding"`
+	Size       int     `json:"size"`
+	Depth      int     `json:"depth"`
+	Iterations int     `json:"iterations"`
+	NsPerOp    int64   `json:"ns_per_op"`
+	MBPerSec   float64 `json:"mb_per_s"`
+	Found      bool    `json:"found"`
+}
+
+// decoderResult is the throughput of one decoder called on its own.
+type decoderResult struct {
+	Decoder string `json:"decoder"`
+	// the encoding of the input: the decoder's own, or plain when nothing
+	// encodes for it
+	Input      string  `json:"input"`
+	Size       int     `json:"size"`
+	Iterations int     `json:"iterations"`
+	NsPerOp    int64   `json:"ns_per_op"`
+	MBPerSec   float64 `json:"mb_per_s"`
+}
+
+type benchReport struct {
+	Searches []benchResult   `json:"searches"`
+	Decoders []decoderResult `json:"decoders"`
+}
+
+// runBench implements "flagrep bench": it searches synthetic corpora with a
+// flag hidden behind each encoding and reports the throughput per depth,
+// then times every decoder on its own.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	sizesFlag := fs.String("sizes", "4KB,64KB,1MB", "Comma-separated corpus sizes")
+	depthsFlag := fs.String("depths", "1,2", "Comma-separated decoder depths to search with")
+	encodingsFlag := fs.String("encodings", "", "Comma-separated encodings of the corpus: plain, a decoder name, or a chain like rot13+base64 (default: plain and every decoder)")
+	decodersFlag := fs.String("decoders", "", "Comma-separated decoders to time on their own (default: every decoder)")
+	minTime := fs.Duration("time", 100*time.Millisecond, "Minimum time to spend on each measurement")
+	jsonOut := fs.Bool("json", false, "Print the results as a JSON array")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep bench [-sizes LIST] [-depths LIST] [-encodings LIST] [-decoders LIST] [-time D] [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var sizes, depths []int
+	for _, s := range strings.Split(*sizesFlag, ",") {
+		size, err := parseSize(s)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: -sizes: %v\n", err)
+			return 2
+		}
+		sizes = append(sizes, size)
+	}
+	for _, s := range strings.Split(*depthsFlag, ",") {
+		depth, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || depth < 0 {
+			fmt.Fprintf(stderr, "Error: -depths: invalid depth %q\n", s)
+			return 2
+		}
+		depths = append(depths, depth)
+	}
+	encodings := []string{"plain"}
+	if *encodingsFlag != "" {
+		encodings = strings.Split(*encodingsFlag, ",")
+	} else {
+		encodings = append(encodings, slices.Sorted(maps.Keys(flagrep.Encoders()))...)
+	}
+	for _, enc := range encodings {
+		if _, err := encodeBench("", enc); err != nil {
+			fmt.Fprintf(stderr, "Error: -encodings: %v\n", err)
+			return 2
+		}
+	}
+
+	decoders := flagrep.Decoders()
+	decoderNames := slices.Sorted(maps.Keys(decoders))
+	if *decodersFlag != "" {
+		decoderNames = strings.Split(*decodersFlag, ",")
+		for _, name := range decoderNames {
+			if decoders[name] == nil {
+				fmt.Fprintf(stderr, "Error: -decoders: unknown decoder %q\n", name)
+				return 2
+			}
+		}
+	}
+
+	var results []benchResult
+	var decoderResults []decoderResult
+	for _, size := range sizes {
+		plain := benchCorpus(size)
+		for _, enc := range encodings {
+			content, _ := encodeBench(plain, enc)
+			for _, depth := range depths {
+				results = append(results, measure(content, enc, size, depth, *minTime))
+			}
+		}
+		for _, name := range decoderNames {
+			decoderResults = append(decoderResults, measureDecoder(plain, name, decoders[name], size, *minTime))
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(benchReport{Searches: results, Decoders: decoderResults})
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "encoding\tsize\tdepth\titerations\ttime/op\tMB/s\tfound")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%.2f\t%v\n", r.Encoding, formatSize(r.Size), r.Depth, r.Iterations, time.Duration(r.NsPerOp).Round(time.Microsecond), r.MBPerSec, r.Found)
+	}
+	tw.Flush()
+
+	// the overall figure per depth is what regresses visibly
+	fmt.Fprintln(stdout)
+	for _, depth := range depths {
+		var bytes, ns float64
+		for _, r := range results {
+			if r.Depth == depth {
+				bytes += float64(r.Size)
+				ns += float64(r.NsPerOp)
+			}
+		}
+		fmt.Fprintf(stdout, "depth %d: %.2f MB/s overall\n", depth, bytes/1e6/(ns/1e9))
+	}
+
+	fmt.Fprintln(stdout)
+	tw = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "decoder\tinput\tsize\titerations\ttime/op\tMB/s")
+	for _, r := range decoderResults {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%.2f\n", r.Decoder, r.Input, formatSize(r.Size), r.Iterations, time.Duration(r.NsPerOp).Round(time.Microsecond), r.MBPerSec)
+	}
+	tw.Flush()
+	return 0
+}
+
+// measureDecoder calls decode on the corpus until minTime has passed. The
+// corpus is encoded for the decoder when an encoder of the same name exists,
+// so that it does its full work rather than bail out early.
+func measureDecoder(plain, name string, decode flagrep.DecoderFunc, size int, minTime time.Duration) decoderResult {
+	input, content := "plain", plain
+	if encode := flagrep.Encoders()[name]; encode != nil {
+		input, content = name, encode(plain)
+	}
+
+	iterations := 0
+	start := time.Now()
+	for iterations == 0 || time.Since(start) < minTime {
+		decode(content)
+		iterations++
+	}
+	elapsed := time.Since(start)
+	return decoderResult{
+		Decoder:    name,
+		Input:      input,
+		Size:       size,
+		Iterations: iterations,
+		NsPerOp:    elapsed.Nanoseconds() / int64(iterations),
+		MBPerSec:   float64(size) * float64(iterations) / 1e6 / elapsed.Seconds(),
+	}
+}
+
+// measure searches content at depth until minTime has passed.
+func measure(content, encoding string, size, depth int, minTime time.Duration) benchResult {
+	var found atomic.Bool
+	searcher := flagrep.NewSearcher(nil, benchFlag, false, true, 1, depth, 0, 0, false)
+	searcher.Logger = slog.New(slog.DiscardHandler)
+	searcher.Output = foundOutput{&found}
+
+	data := []byte(content)
+	iterations := 0
+	start := time.Now()
+	for iterations == 0 || time.Since(start) < minTime {
+		searcher.ScanBytes(context.Background(), data, "bench")
+		iterations++
+	}
+	elapsed := time.Since(start)
+	return benchResult{
+		Encoding:   encoding,
+		Size:       size,
+		Depth:      depth,
+		Iterations: iterations,
+		NsPerOp:    elapsed.Nanoseconds() / int64(iterations),
+		MBPerSec:   float64(size) * float64(iterations) / 1e6 / elapsed.Seconds(),
+		Found:      found.Load(),
+	}
+}
+
+type foundOutput struct {
+	found *atomic.Bool
+}
+
+func (o foundOutput) Begin(info flagrep.ScanInfo)              {}
+func (o foundOutput) Match(m flagrep.Match)                    { o.found.Store(true) }
+func (o foundOutput) Truncated(file string, decoders []string) {}
+func (o foundOutput) End(summary flagrep.ScanSummary)          {}
+
+// benchCorpus returns size bytes of word salad with benchFlag in the middle.
+// The seed is fixed so runs compare.
+func benchCorpus(size int) string {
+	rng := rand.New(rand.NewPCG(1, 1))
+	var b strings.Builder
+	for b.Len() < size/2 {
+		b.WriteString(benchWords[rng.IntN(len(benchWords))])
+		b.WriteByte(' ')
+	}
+	b.WriteString(benchFlag)
+	for b.Len() < size {
+		b.WriteByte(' ')
+		b.WriteString(benchWords[rng.IntN(len(benchWords))])
+	}
+	return b.String()[:max(size, len(benchFlag))]
+}
+
+// encodeBench applies an encoding from -encodings: "plain" or encoders
+// joined by "+", applied left to right.
+func encodeBench(text, encoding string) (string, error) {
+	if encoding == "plain" {
+		return text, nil
+	}
+	return flagrep.EncodeChain(text, strings.Split(encoding, "+"))
+}
+
+// parseSize reads sizes like 512, 4KB or 1MB (powers of 1024).
+func parseSize(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := 1
+	switch {
+	case strings.HasSuffix(s, "MB"):
+		mult, s = 1<<20, strings.TrimSuffix(s, "MB")
+	case strings.HasSuffix(s, "KB"):
+		mult, s = 1<<10, strings.TrimSuffix(s, "KB")
+	case strings.HasSuffix(s, "B"):
+		s = strings.TrimSuffix(s, "B")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return strconv.Itoa(n>>20) + "MB"
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return strconv.Itoa(n>>10) + "KB"
+	}
+	return strconv.Itoa(n) + "B"
+}
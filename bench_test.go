@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+func TestRunBench(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-sizes", "1KB", "-depths", "1,2", "-encodings", "plain,base64,rot13+hex_without_spaces", "-time", "1ms", "-json"}
+	if status := runBench(args, &stdout, &stderr); status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	var report benchReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Searches) != 6 {
+		t.Fatalf("got %d search results, want 6", len(report.Searches))
+	}
+	for _, r := range report.Searches {
+		// the two-step chain needs depth 2
+		want := r.Depth == 2 || !strings.Contains(r.Encoding, "+")
+		if r.Found != want || r.Iterations == 0 || r.Size != 1024 {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+
+	// one row per decoder
+	seen := make(map[string]bool)
+	for _, r := range report.Decoders {
+		if seen[r.Decoder] || r.Iterations == 0 || r.Size != 1024 {
+			t.Errorf("unexpected decoder result %+v", r)
+		}
+		seen[r.Decoder] = true
+	}
+	if len(seen) != len(flagrep.Decoders()) {
+		t.Errorf("timed %d decoders, want %d", len(seen), len(flagrep.Decoders()))
+	}
+	if !seen["base64"] || !seen["reverse"] {
+		t.Errorf("missing decoders: %v", seen)
+	}
+
+	stdout.Reset()
+	if status := runBench([]string{"-sizes", "512", "-depths", "1", "-encodings", "plain", "-decoders", "hex_with_spaces", "-time", "1ms"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "decoder") || !strings.Contains(stdout.String(), "\nhex_with_spaces ") {
+		t.Errorf("no decoder table in %q", stdout.String())
+	}
+
+	if status := runBench([]string{"-encodings", "nope"}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 for an unknown encoding, got %d", status)
+	}
+	if status := runBench([]string{"-decoders", "nope"}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 for an unknown decoder, got %d", status)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int{"512": 512, "4KB": 4096, "1mb": 1 << 20, "10B": 10} {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v", in, got, err)
+		}
+	}
+	if _, err := parseSize("big"); err == nil {
+		t.Error("parseSize accepted nonsense")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// ciOutput reports the findings of a -ci run with their secrets redacted,
+// plus GitHub Actions annotations when running there, and a summary line.
+type ciOutput struct {
+	mu       sync.Mutex
+	w        io.Writer
+	github   bool
+	findings int
+	files    map[string]bool
+	// file and text of every finding, decoded copies of a secret are
+	// reported once, with the shortest chain since that comes first
+	seen map[string]bool
+}
+
+func newCIOutput(w io.Writer, github bool) *ciOutput {
+	return &ciOutput{w: w, github: github, files: make(map[string]bool), seen: make(map[string]bool)}
+}
+
+func (o *ciOutput) Begin(info flagrep.ScanInfo) {}
+
+func (o *ciOutput) Match(m flagrep.Match) {
+	kind := flagrep.SecretKind(m.Text)
+	if kind == "" {
+		kind = "match of " + m.Pattern
+	}
+	location := m.File
+	if m.Line > 0 {
+		location += ":" + strconv.Itoa(m.Line)
+	}
+	msg := fmt.Sprintf("%s %s (decoders: %s, confidence %.2f)", kind, redact(m.Text), decoderChain(m.Decoders), m.Confidence)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := m.File + "\x00" + m.Text
+	if o.seen[key] {
+		return
+	}
+	o.seen[key] = true
+	o.findings++
+	o.files[m.File] = true
+	fmt.Fprintf(o.w, "%s: %s\n", location, msg)
+	if o.github {
+		props := "file=" + ghEscapeProperty(m.File)
+		if m.Line > 0 {
+			props += ",line=" + strconv.Itoa(m.Line)
+		}
+		fmt.Fprintf(o.w, "::error %s,title=flagrep::%s\n", props, ghEscapeData(msg))
+	}
+}
+
+func (o *ciOutput) Truncated(file string, decoders []string) {}
+
+func (o *ciOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.findings == 0 {
+		fmt.Fprintf(o.w, "flagrep: no findings in %d files\n", summary.FilesScanned)
+		return
+	}
+	fmt.Fprintf(o.w, "flagrep: %d findings in %d of %d files\n", o.findings, len(o.files), summary.FilesScanned)
+}
+
+// Findings is the number of matches reported so far.
+func (o *ciOutput) Findings() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.findings
+}
+
+// redact keeps just enough of a secret to find it again; CI logs are often
+// readable by more people than the repository.
+func redact(s string) string {
+	if len(s) < 12 || strings.HasPrefix(s, "-----BEGIN") {
+		return s
+	}
+	return s[:4] + strings.Repeat("*", len(s)-8) + s[len(s)-4:]
+}
+
+// GitHub workflow command escaping, see "Workflow commands for GitHub Actions"
+func ghEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func ghEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// red, what the match highlight has always been
+const defaultMatchColor = "31"
+
+// matchColor returns the SGR parameters used to highlight matches, or "" when
+// output should not be colored. mode is the value of -color.
+func matchColor(mode string) (string, error) {
+	switch mode {
+	case "never":
+		return "", nil
+	case "always":
+	case "auto", "":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok || !isTerminal(os.Stdout) {
+			return "", nil
+		}
+	default:
+		return "", fmt.Errorf("invalid -color value %q (want auto, always or never)", mode)
+	}
+
+	// same idea as grep's GREP_COLOR, e.g. FLAGREP_COLOR="1;32"
+	if custom := os.Getenv("FLAGREP_COLOR"); custom != "" {
+		if !validSGR(custom) {
+			return "", fmt.Errorf("invalid FLAGREP_COLOR %q, expected SGR parameters like \"1;32\"", custom)
+		}
+		return custom, nil
+	}
+	return defaultMatchColor, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// validSGR reports whether s is a list of numeric SGR parameters.
+func validSGR(s string) bool {
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
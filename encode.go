@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// runEncode implements "flagrep encode -chain A,B TEXT...".
+func runEncode(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("encode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	chainFlag := fs.String("chain", "", "Comma-separated encoders to apply in order, e.g. rot13,base64")
+	verify := fs.Bool("verify", false, "Decode the result with the reversed chain and fail unless it round-trips")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep encode -chain A,B [-verify] TEXT... (or TEXT on stdin)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		return 2
+	}
+	if *chainFlag == "" {
+		fs.Usage()
+		return 2
+	}
+	chain := strings.Split(*chainFlag, ",")
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		inputs = []string{strings.TrimSuffix(string(data), "\n")}
+	}
+
+	status := 0
+	for _, input := range inputs {
+		encoded, err := flagrep.EncodeChain(input, chain)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		if *verify {
+			decoded, err := flagrep.DecodeChain(encoded, chain)
+			if err != nil || decoded != input {
+				fmt.Fprintf(stderr, "Error: %q does not round-trip through %s (got %q, %v)\n", input, *chainFlag, decoded, err)
+				status = 1
+			}
+		}
+		fmt.Fprintln(stdout, encoded)
+	}
+	return status
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunEncode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := runEncode([]string{"--chain", "rot13,base64", "flag{test}"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "c3ludHtncmZnfQ==" {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+	// space_removal also drops the spaces that were there before
+	if status := runEncode([]string{"-verify", "-chain", "space_removal", "a b"}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected -verify to fail, got exit status %d", status)
+	}
+	if status := runEncode([]string{"-chain", "nope", "x"}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 for an unknown encoder, got %d", status)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// runHeaders implements "flagrep headers FILE...".
+func runHeaders(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("headers", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOut := fs.Bool("json", false, "Print one JSON object per file")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep headers [-json] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	enc := json.NewEncoder(stdout)
+	for i, path := range fs.Args() {
+		h, err := flagrep.ParseFileHeader(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		if *jsonOut {
+			enc.Encode(h)
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprint(stdout, flagrep.FormatHeader(h))
+	}
+	return status
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+func TestRunHeadersRejectsText(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "encodedgrep_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "plain.txt")
+	os.WriteFile(path, []byte("just text"), 0644)
+
+	var stdout, stderr bytes.Buffer
+	if status := runHeaders([]string{path}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1, got %d", status)
+	}
+	if !strings.Contains(stderr.String(), flagrep.ErrUnknownFormat.Error()) {
+		t.Errorf("unexpected error output: %q", stderr.String())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// newLogger returns the logger for diagnostics. It should write to stderr so
+// that walk errors and skip reasons never end up interleaved with results.
+//
+// verbosity 0 shows warnings and errors, 1 (-v) adds informational messages
+// such as unreadable files, 2 (-vv) adds per-file debug details and 3 (-vvv)
+// traces every decoded state.
+func newLogger(w io.Writer, verbosity int, format string) (*slog.Logger, error) {
+	level := slog.LevelWarn
+	switch {
+	case verbosity >= 3:
+		level = flagrep.LevelTrace
+	case verbosity == 2:
+		level = slog.LevelDebug
+	case verbosity == 1:
+		level = slog.LevelInfo
+	}
+
+	// slog would call it DEBUG-4
+	traceName := func(a slog.Attr) slog.Attr {
+		if a.Key == slog.LevelKey && a.Value.Any() == flagrep.LevelTrace {
+			return slog.String(slog.LevelKey, "TRACE")
+		}
+		return a
+	}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				return traceName(a)
+			},
+		})), nil
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+			Level: level,
+			// timestamps are noise for an interactive tool
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return traceName(a)
+			},
+		})), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
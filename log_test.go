@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+func TestTraceLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString([]byte("the secret"))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	logger, err := newLogger(&logs, 3, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	searcher := flagrep.NewSearcher([]string{path}, "secret", false, true, 1, 1, 5, 5, false)
+	searcher.Logger = logger
+	if err := searcher.RunContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), `"level":"TRACE","msg":"decoded","path":"`+path+`","decoders":"base64"`) {
+		t.Errorf("missing trace record:\n%s", logs.String())
+	}
+
+	logs.Reset()
+	logger, _ = newLogger(&logs, 2, "text")
+	searcher.Logger = logger
+	searcher.Run()
+	if strings.Contains(logs.String(), "TRACE") || !strings.Contains(logs.String(), "level=DEBUG msg=scanned") {
+		t.Errorf("unexpected -vv output:\n%s", logs.String())
+	}
+}
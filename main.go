@@ -3,63 +3,407 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// overridden at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// characters of context shown when none of -A, -B and -C is given
+const (
+	defaultContextBefore = 10
+	defaultContextAfter  = 30
 )
 
+// -ci only fails the build on matches at least this confident, unless
+// -min-confidence is given
+const ciMinConfidence = 0.5
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "headers":
+			os.Exit(runHeaders(os.Args[2:], os.Stdout, os.Stderr))
+		case "strings":
+			os.Exit(runStrings(os.Args[2:], os.Stdout, os.Stderr))
+		case "analyze":
+			os.Exit(runAnalyze(os.Args[2:], os.Stdout, os.Stderr))
+		case "encode":
+			os.Exit(runEncode(os.Args[2:], os.Stdout, os.Stderr))
+		case "bench":
+			os.Exit(runBench(os.Args[2:], os.Stdout, os.Stderr))
+		case "serve":
+			os.Exit(runServe(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	recursive := flag.Bool("r", false, "Recursively search directories")
 	ignoreCase := flag.Bool("i", false, "Ignore case")
-	workers := flag.Int("workers", 10, "Concurrency limit")
+	workers := flag.Int("workers", 0, "Concurrency limit (default: one per CPU, more while small files keep them all busy)")
+	showStats := flag.Bool("stats", false, "Print per-worker throughput on stderr when the scan ends")
 	depth := flag.Int("depth", 2, "Decoder combination depth")
-	verbose := flag.Bool("v", false, "Verbose output")
+	verbose := flag.Bool("v", false, "Verbose output: report skipped files and other diagnostics on stderr")
+	veryVerbose := flag.Bool("vv", false, "Debug output: also report per-file details on stderr")
+	traceVerbose := flag.Bool("vvv", false, "Trace output: also report every decoded state on stderr")
+	logFormat := flag.String("log-format", "text", "Diagnostics format on stderr: text, json")
+	baselinePath := flag.String("baseline", "", "Only report matches missing from this previous -json output")
+	ignorePath := flag.String("ignore-file", "", "File of match fingerprints or regexes to silence (default ./"+flagrep.IgnoreFileName+" if present)")
+	hashListPath := flag.String("hashlist", "", "Skip files whose MD5, SHA-1 or SHA-256 is in this file (plain or NSRL CSV)")
+	yaraRules := flag.String("yara", "", "Also run these YARA rules through an installed engine")
+	yaraBin := flag.String("yara-bin", "yara", "YARA engine to run for -yara: yara, or yr for yara-x")
+	unpack := flag.Bool("unpack", false, "Also search UPX-packed executables as an installed upx unpacks them")
+	upxBin := flag.String("upx-bin", "upx", "upx to run for -unpack")
+	certificates := flag.Bool("certs", false, "Report X.509 certificates and private keys, raw or decoded")
+	stackStrings := flag.Bool("stack-strings", false, "Also search strings that x86/x64 executables build on the stack")
+	dns := flag.Bool("dns", false, "Also search the subdomain labels of the queries to each domain in DNS logs or zone data, joined and decoded")
+	reassemble := flag.Bool("reassemble", false, "Also search payloads split into encoded chunks over many log lines, e.g. DNS exfiltration")
+	staged := flag.Bool("staged", false, "Scan the files staged in git, for pre-commit hooks")
+	files0From := flag.String("files0-from", "", "Also scan the NUL-separated file names read from FILE, - for stdin")
+	listFiles := flag.Bool("list-files", false, "Only list the files that would be scanned and why others would be skipped")
+	adaptive := flag.Bool("adaptive", false, "Try decoders that led to matches first and skip fruitless ones below depth 1")
+	statsPath := flag.String("decoder-stats", "", "Load and save decoder hit rates in FILE across runs (implies -adaptive)")
+	explain := flag.Bool("explain", false, "Show each decoding step that led to a match")
+	enableSolver := flag.String("enable-solver", "", "Comma-separated expensive decoders to add: substitution")
+	bruteDepth := flag.Int("brute-depth", 1, "Decoder levels the -enable-solver decoders run on, at most -depth")
+	maxSolverRuns := flag.Int("max-solver-runs", 100, "Most times the -enable-solver decoders run in a scan, 0 for no limit")
+	charset := flag.String("charset", "auto", "Charset of the input, transcoded to UTF-8 before matching: auto, "+strings.Join(slices.Sorted(maps.Keys(flagrep.Charsets)), ", "))
+	jsonFields := flag.String("json-field", "", "Treat input as JSON Lines and search only these comma-separated fields, e.g. message,request.body")
+	maxResults := flag.Int("max-results", 0, "Stop the scan after N matches (0: no limit)")
+	maxTime := flag.Duration("max-time", 0, "Stop the scan after DURATION, e.g. 30s (0: no limit)")
+	minConfidence := flag.Float64("min-confidence", 0, "Drop matches with a confidence score below this (0-1)")
+	ci := flag.Bool("ci", false, "CI gate: scan PATH... (default .) for secrets, print a redacted summary and exit 1 on findings")
+	presetName := flag.String("preset", "", "Search a ready-made pattern instead of PATTERN, e.g. ctf or ctf:picoCTF")
+	jsonOut := flag.Bool("json", false, "Emit results as a JSON Lines stream (same as -format json)")
+	format := flag.String("format", "text", "Output format: text, json, csv, sarif, markdown")
+	heading := flag.Bool("heading", false, "Group text output by file, printing each file name once")
+	notifyURL := flag.String("notify-url", "", "POST matches in batches to this webhook URL")
+	notifyFormat := flag.String("notify-format", "json", "Webhook payload format: json, slack, discord")
+	outputPath := flag.String("O", "", "Write results to FILE, replaced atomically when the scan completes")
+	colorMode := flag.String("color", "auto", "Highlight matches: auto, always, never (FLAGREP_COLOR sets the SGR color)")
 
 	var afterContext, beforeContext int
-	flag.IntVar(&afterContext, "A", 0, "Print NUM characters of trailing context")
-	flag.IntVar(&beforeContext, "B", 0, "Print NUM characters of leading context")
+	flag.IntVar(&afterContext, "A", 0, "Print NUM characters of trailing context (default 30 without -B and -C)")
+	flag.IntVar(&beforeContext, "B", 0, "Print NUM characters of leading context (default 10 without -A and -C)")
 	var context int
 	flag.IntVar(&context, "C", 0, "Print NUM characters of output context")
+	noContext := flag.Bool("no-context", false, "Print only the matched text, same as -C 0")
+
+	// GNU-style long names
+	flag.BoolVar(recursive, "recursive", false, "Same as -r")
+	flag.BoolVar(ignoreCase, "ignore-case", false, "Same as -i")
+	flag.BoolVar(verbose, "verbose", false, "Same as -v")
+	flag.IntVar(&afterContext, "after-context", 0, "Same as -A")
+	flag.IntVar(&beforeContext, "before-context", 0, "Same as -B")
+	flag.IntVar(&context, "context", 0, "Same as -C")
+	flag.StringVar(outputPath, "output", "", "Same as -O")
 
-	flag.Parse()
+	flag.CommandLine.Parse(normalizeArgs(flag.CommandLine, os.Args[1:]))
+
+	depthSet, minConfidenceSet := false, false
+	var afterSet, beforeSet, contextSet bool
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "depth":
+			depthSet = true
+		case "min-confidence":
+			minConfidenceSet = true
+		case "A", "after-context":
+			afterSet = true
+		case "B", "before-context":
+			beforeSet = true
+		case "C", "context":
+			contextSet = true
+		}
+	})
 
 	args := flag.Args()
+	if *ci {
+		if *presetName == "" {
+			*presetName = "secrets"
+		}
+		if len(args) == 0 && !*staged && *files0From == "" {
+			args = []string{"."}
+		}
+		*recursive = true
+		if !minConfidenceSet {
+			*minConfidence = ciMinConfidence
+		}
+	}
+	var preset *flagrep.Preset
+	if *presetName != "" {
+		p, err := flagrep.LookupPreset(*presetName)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		preset = &p
+		// the preset is the pattern, every argument is a path
+		args = append([]string{p.Name}, args...)
+		if !depthSet {
+			*depth = p.Depth
+		}
+	}
 	if len(args) < 1 {
-		fmt.Println("Usage: flagrep [options] PATTERN [FILE...] OR flagrep [options] PATTERN < stdin")
+		fmt.Fprintln(os.Stderr, "Usage: flagrep [options] PATTERN [FILE...] OR flagrep [options] PATTERN < stdin")
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	pattern := args[0]
 	paths := args[1:]
+	if *staged || *files0From != "" {
+		var listed []string
+		if *staged {
+			files, err := stagedFiles()
+			if err != nil {
+				fatalf("%v", err)
+			}
+			listed = append(listed, files...)
+		}
+		if *files0From != "" {
+			files, err := readFiles0(*files0From)
+			if err != nil {
+				fatalf("reading file names: %v", err)
+			}
+			listed = append(listed, files...)
+		}
+		if len(listed) == 0 && len(paths) == 0 {
+			// nothing staged; without paths the scan would read stdin
+			return
+		}
+		paths = append(paths, listed...)
+	}
 
-	// if C is set, A and B are set to C, just like in grep
-	if context > 0 {
-		if afterContext == 0 {
+	// if C is set, A and B are set to C unless given too, just like in grep
+	if contextSet {
+		if !afterSet {
 			afterContext = context
 		}
-		if beforeContext == 0 {
+		if !beforeSet {
 			beforeContext = context
 		}
 	}
-	// default is 10 chars before and 30 chars after
-	if afterContext == 0 && beforeContext == 0 && context == 0 {
-		beforeContext = 10
-		afterContext = 30
+	// without any of them, show a little context; -A 0 -B 0 means none
+	if !afterSet && !beforeSet && !contextSet {
+		beforeContext = defaultContextBefore
+		afterContext = defaultContextAfter
+	}
+	if *noContext {
+		beforeContext, afterContext = 0, 0
 	}
 
 	caseSensitive := !*ignoreCase
 
-	searcher := NewSearcher(paths, pattern, *recursive, caseSensitive, *workers, *depth, beforeContext, afterContext, *verbose)
+	if *jsonOut {
+		*format = "json"
+	}
+	if *ci && *heading {
+		fatalf("-heading does not apply to -ci")
+	}
+	if *heading && *format != "text" {
+		fatalf("-heading only applies to text output")
+	}
+	color, err := matchColor(*colorMode)
+	if err != nil {
+		fatalf("%v", err)
+	}
 
+	verbosity := 0
 	if *verbose {
-		fmt.Printf("Starting search for pattern %q (Recursive: %v, Depth: %d)\n", pattern, *recursive, *depth)
+		verbosity = 1
+	}
+	if *veryVerbose {
+		verbosity = 2
+	}
+	if *traceVerbose {
+		verbosity = 3
+	}
+	logger, err := newLogger(os.Stderr, verbosity, *logFormat)
+	if err != nil {
+		fatalf("%v", err)
 	}
 
-	// just in case
-	fmt.Println("*Expect false positives")
+	searcher := flagrep.NewSearcher(paths, pattern, *recursive, caseSensitive, *workers, *depth, beforeContext, afterContext, verbosity > 0)
+	searcher.Logger = logger
+	if preset != nil {
+		if err := searcher.UseRegexp(preset.Pattern); err != nil {
+			fatalf("preset %s: %v", preset.Name, err)
+		}
+		searcher.Preset = preset.Name
+		if preset.Decoders != nil {
+			searcher.Decoders = make(map[string]flagrep.DecoderFunc)
+			for _, name := range preset.Decoders {
+				searcher.Decoders[name] = flagrep.Decoders()[name]
+			}
+		}
+	}
+	if err := flagrep.EnableSolvers(searcher.Decoders, *enableSolver); err != nil {
+		fatalf("%v", err)
+	}
+	if *charset != "auto" {
+		if _, ok := flagrep.Charsets[*charset]; !ok {
+			fatalf("unknown charset %q", *charset)
+		}
+		searcher.Charset = *charset
+	}
+	searcher.BruteDepth = *bruteDepth
+	searcher.MaxSolverRuns = *maxSolverRuns
+	searcher.MinConfidence = *minConfidence
+	searcher.MaxResults = *maxResults
+	if *jsonFields != "" {
+		searcher.JSONFields = strings.Split(*jsonFields, ",")
+	}
+	searcher.MaxTime = *maxTime
+	searcher.StackStrings = *stackStrings
+	searcher.Reassemble = *reassemble
+	searcher.DNS = *dns
+	searcher.Explain = *explain
+	searcher.Certificates = *certificates
+	if *statsPath != "" {
+		searcher.Stats, err = flagrep.LoadDecoderStats(*statsPath)
+		if err != nil {
+			fatalf("loading decoder stats: %v", err)
+		}
+	} else if *adaptive {
+		searcher.Stats = flagrep.NewDecoderStats()
+	}
+	if searcher.Stats != nil {
+		searcher.Stats.Prune = true
+	}
+	switch {
+	case *ignorePath != "":
+		searcher.Ignore, err = flagrep.LoadIgnoreFile(*ignorePath)
+	default:
+		if _, statErr := os.Stat(flagrep.IgnoreFileName); statErr == nil {
+			searcher.Ignore, err = flagrep.LoadIgnoreFile(flagrep.IgnoreFileName)
+		}
+	}
+	if err != nil {
+		fatalf("loading ignore file: %v", err)
+	}
+	if *baselinePath != "" {
+		searcher.Baseline, err = flagrep.LoadBaseline(*baselinePath)
+		if err != nil {
+			fatalf("loading baseline: %v", err)
+		}
+	}
+
+	if *hashListPath != "" {
+		searcher.HashList, err = flagrep.LoadHashList(*hashListPath)
+		if err != nil {
+			fatalf("loading hash list: %v", err)
+		}
+		logger.Info("loaded hash list", "hashes", searcher.HashList.Len())
+	}
+
+	if *listFiles {
+		if err := searcher.ListFiles(os.Stdout); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
+
+	if *yaraRules != "" {
+		searcher.Yara, err = flagrep.NewYaraRunner(*yaraBin, *yaraRules)
+		if err != nil {
+			fatalf("%v", err)
+		}
+	}
+	if *unpack {
+		searcher.UPX, err = flagrep.NewUPXRunner(*upxBin)
+		if err != nil {
+			fatalf("%v", err)
+		}
+	}
+
+	var notifier *notifyOutput
+	if *notifyURL != "" {
+		notifier, err = newNotifyOutput(*notifyURL, *notifyFormat, logger)
+		if err != nil {
+			fatalf("%v", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	var resultFile *atomicFile
+	if *outputPath != "" {
+		resultFile, err = createAtomic(*outputPath)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		w = resultFile
+		if *colorMode == "auto" {
+			color = ""
+		}
+	}
 
-	err := searcher.Run()
+	output, err := newOutput(*format, w, color)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		if resultFile != nil {
+			resultFile.Abort()
+		}
+		fatalf("%v", err)
+	}
+	if *heading {
+		output = newHeadingOutput(w, color)
+	}
+	var gate *ciOutput
+	if *ci {
+		github := os.Getenv("GITHUB_ACTIONS") == "true"
+		if *format == "text" {
+			gate = newCIOutput(w, github)
+			output = gate
+		} else {
+			// keep stdout for the report, e.g. SARIF for code scanning
+			if sarif, ok := output.(*sarifOutput); ok {
+				sarif.redact = true
+			}
+			gate = newCIOutput(os.Stderr, github)
+			output = multiOutput{output, gate}
+		}
+	}
+	if notifier != nil {
+		output = multiOutput{output, notifier}
+	}
+	if *showStats {
+		output = multiOutput{output, statsOutput{os.Stderr}}
+	}
+	searcher.Output = output
+
+	logger.Info("starting search", "pattern", pattern, "recursive", *recursive, "depth", *depth)
+
+	err = searcher.Run()
+	if searcher.Yara != nil {
+		searcher.Yara.Close()
+	}
+	if err != nil {
+		if resultFile != nil {
+			resultFile.Abort()
+		}
+		fatalf("%v", err)
+	}
+	if resultFile != nil {
+		if err := resultFile.Commit(); err != nil {
+			fatalf("writing %s: %v", *outputPath, err)
+		}
+	}
+	if *statsPath != "" {
+		if err := searcher.Stats.Save(*statsPath); err != nil {
+			fatalf("saving decoder stats: %v", err)
+		}
+	}
+	if gate != nil && gate.Findings() > 0 {
 		os.Exit(1)
 	}
 }
+
+// fatalf reports a fatal error on stderr and exits.
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// markdownOutput collects matches and writes a report grouped by file when
+// the scan ends, ready to paste into writeups and tickets.
+type markdownOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	info    flagrep.ScanInfo
+	byFile  map[string][]flagrep.Match
+	ordered []string
+}
+
+func newMarkdownOutput(w io.Writer) *markdownOutput {
+	return &markdownOutput{w: w, byFile: make(map[string][]flagrep.Match)}
+}
+
+func (o *markdownOutput) Begin(info flagrep.ScanInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.info = info
+}
+
+func (o *markdownOutput) Match(m flagrep.Match) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.byFile[m.File]; !ok {
+		o.ordered = append(o.ordered, m.File)
+	}
+	o.byFile[m.File] = append(o.byFile[m.File], m)
+}
+
+func (o *markdownOutput) Truncated(file string, decoders []string) {}
+
+func (o *markdownOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# flagrep report\n\n")
+	fmt.Fprintf(&b, "- Pattern: `%s`\n", o.info.Pattern)
+	if o.info.Preset != "" {
+		fmt.Fprintf(&b, "- Preset: %s\n", o.info.Preset)
+	}
+	fmt.Fprintf(&b, "- Files scanned: %d\n", summary.FilesScanned)
+	fmt.Fprintf(&b, "- Matches: %d in %d file(s)\n", summary.Matches, len(o.ordered))
+	fmt.Fprintf(&b, "- Decoder depth: %d\n", o.info.Depth)
+	if summary.StoppedBy != "" {
+		fmt.Fprintf(&b, "- Truncated: stopped by `-%s`\n", summary.StoppedBy)
+	}
+
+	files := slices.Clone(o.ordered)
+	slices.Sort(files)
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n## `%s`\n", file)
+		for i, m := range o.byFile[file] {
+			fmt.Fprintf(&b, "\n**Match %d** - decoders: `%s`", i+1, decoderChain(m.Decoders))
+			if alts := allChains(m)[1:]; len(alts) > 0 {
+				fmt.Fprintf(&b, " (also `%s`)", strings.Join(alts, "`, `"))
+			}
+			fmt.Fprintf(&b, ", offset %d\n\n", m.Offset)
+			context := strings.TrimRight(m.Before+m.Text+m.After, "\r\n")
+			fence := codeFence(context)
+			fmt.Fprintf(&b, "%s\n%s\n%s\n", fence, context, fence)
+			if len(m.Steps) > 0 {
+				b.WriteString("\n")
+				for _, step := range m.Steps {
+					name := "input"
+					if step.Decoder != "" {
+						name = step.Decoder
+					}
+					fmt.Fprintf(&b, "1. %s: `` %s ``\n", name, step.Snippet)
+				}
+			}
+		}
+	}
+
+	io.WriteString(o.w, b.String())
+}
+
+// codeFence returns a backtick fence longer than any backtick run in s, so
+// the context can never close its own code block.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
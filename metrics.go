@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upper bounds in seconds of the scan duration histogram buckets
+var scanDurationBuckets = []float64{0.1, 1, 10, 60, 300, 1800}
+
+// serveMetrics holds the counters of "flagrep serve" that the Searchers do
+// not keep themselves.
+type serveMetrics struct {
+	mu        sync.Mutex
+	scans     map[string]int64 // finished scans by final status
+	matches   map[string]int64 // by pattern
+	durations []int64          // per bucket, plus +Inf
+	sum       float64
+}
+
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		scans:     make(map[string]int64),
+		matches:   make(map[string]int64),
+		durations: make([]int64, len(scanDurationBuckets)+1),
+	}
+}
+
+func (m *serveMetrics) match(pattern string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matches[pattern]++
+}
+
+func (m *serveMetrics) finished(status string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans[status]++
+	seconds := d.Seconds()
+	m.sum += seconds
+	i, _ := slices.BinarySearch(scanDurationBuckets, seconds)
+	m.durations[i]++
+}
+
+// metrics serves the Prometheus text exposition format.
+func (s *server) metrics(w http.ResponseWriter, r *http.Request) {
+	running := 0
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		if j.status().Status == jobRunning {
+			running++
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(w, "flagrep_files_scanned_total", "counter", "Files searched, including uploads.", nil, s.searchMetrics.FilesScanned())
+	writeMetric(w, "flagrep_bytes_scanned_total", "counter", "Bytes of files searched, before decoding.", nil, s.searchMetrics.BytesScanned())
+	writeMetric(w, "flagrep_decode_attempts_total", "counter", "Decoder applications by decoder.", labeled("decoder", s.searchMetrics.DecodeAttempts()), 0)
+	writeMetric(w, "flagrep_scans_running", "gauge", "Scans in progress.", nil, int64(running))
+
+	m := s.serveMetrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeMetric(w, "flagrep_matches_total", "counter", "Matches reported by pattern.", labeled("pattern", m.matches), 0)
+	writeMetric(w, "flagrep_scans_total", "counter", "Finished scans by status.", labeled("status", m.scans), 0)
+
+	fmt.Fprintln(w, "# HELP flagrep_scan_duration_seconds Duration of finished scans.")
+	fmt.Fprintln(w, "# TYPE flagrep_scan_duration_seconds histogram")
+	var cumulative int64
+	for i, le := range scanDurationBuckets {
+		cumulative += m.durations[i]
+		fmt.Fprintf(w, "flagrep_scan_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	cumulative += m.durations[len(scanDurationBuckets)]
+	fmt.Fprintf(w, "flagrep_scan_duration_seconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(w, "flagrep_scan_duration_seconds_sum %s\n", strconv.FormatFloat(m.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "flagrep_scan_duration_seconds_count %d\n", cumulative)
+}
+
+// labeled turns counts by label value into sorted "{label="value"}" series.
+func labeled(label string, counts map[string]int64) map[string]int64 {
+	series := make(map[string]int64, len(counts))
+	for value, n := range counts {
+		series["{"+label+"=\""+escapeLabel(value)+"\"}"] = n
+	}
+	return series
+}
+
+// writeMetric writes one metric family, either a single value or, when
+// series is not nil, one sample per label set.
+func writeMetric(w io.Writer, name, typ, help string, series map[string]int64, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	if series == nil {
+		fmt.Fprintf(w, "%s %d\n", name, value)
+		return
+	}
+	for _, labels := range slices.Sorted(maps.Keys(series)) {
+		fmt.Fprintf(w, "%s%s %d\n", name, labels, series[labels])
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
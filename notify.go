@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+const (
+	notifyBatchSize = 50
+	// batches waiting for the sender; more matches wait in the current batch
+	notifyQueue = 16
+	// Discord rejects messages longer than this
+	discordMaxContent = 2000
+)
+
+// notifyOutput POSTs matches to a webhook in batches while the scan runs,
+// flushing whatever is left when it ends. Batches are sent in the
+// background, so a slow endpoint does not hold up the workers.
+type notifyOutput struct {
+	mu      sync.Mutex
+	url     string
+	format  string
+	client  *http.Client
+	logger  *slog.Logger
+	pattern string
+	batch   []flagrep.Match
+	queue   chan []flagrep.Match
+	done    chan struct{}
+}
+
+func newNotifyOutput(url, format string, logger *slog.Logger) (*notifyOutput, error) {
+	switch format {
+	case "json", "slack", "discord":
+	default:
+		return nil, fmt.Errorf("unknown notification format %q", format)
+	}
+	o := &notifyOutput{
+		url:    url,
+		format: format,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+		queue:  make(chan []flagrep.Match, notifyQueue),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(o.done)
+		for batch := range o.queue {
+			o.send(batch)
+		}
+	}()
+	return o, nil
+}
+
+type notifyJSONPayload struct {
+	Schema  int         `json:"schema"`
+	Type    string      `json:"type"`
+	Pattern string      `json:"pattern"`
+	Matches []jsonMatch `json:"matches"`
+}
+
+func (o *notifyOutput) Begin(info flagrep.ScanInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pattern = info.Pattern
+	if info.Preset != "" {
+		// the expression is too long to read in a chat message
+		o.pattern = info.Preset
+	}
+}
+
+func (o *notifyOutput) Match(m flagrep.Match) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.batch = append(o.batch, m)
+	if len(o.batch) < notifyBatchSize {
+		return
+	}
+	// with the queue full the batch keeps growing until the sender
+	// catches up
+	select {
+	case o.queue <- o.batch:
+		o.batch = nil
+	default:
+	}
+}
+
+func (o *notifyOutput) Truncated(file string, decoders []string) {}
+
+// End sends what is left and waits for every batch to go out.
+func (o *notifyOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	batch := o.batch
+	o.batch = nil
+	o.mu.Unlock()
+
+	if len(batch) > 0 {
+		o.queue <- batch
+	}
+	close(o.queue)
+	<-o.done
+}
+
+func (o *notifyOutput) send(batch []flagrep.Match) {
+	payload, err := o.payload(batch)
+	if err != nil {
+		o.logger.Error("encoding notification", "err", err)
+		return
+	}
+	resp, err := o.client.Post(o.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// the error quotes the URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		o.logger.Warn("sending notification", "host", webhookHost(o.url), "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		o.logger.Warn("sending notification", "host", webhookHost(o.url), "status", resp.Status)
+	}
+}
+
+// webhookHost returns the scheme and host of a webhook URL for logging;
+// Slack and Discord put the secret token in the path.
+func webhookHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (o *notifyOutput) payload(batch []flagrep.Match) ([]byte, error) {
+	switch o.format {
+	case "slack":
+		return json.Marshal(map[string]string{"text": slackEscaper.Replace(o.summarize(batch, 0))})
+	case "discord":
+		return json.Marshal(map[string]any{
+			"content": o.summarize(batch, discordMaxContent),
+			// a match saying @everyone must not ping anyone
+			"allowed_mentions": map[string][]string{"parse": {}},
+		})
+	default:
+		matches := make([]jsonMatch, len(batch))
+		for i, m := range batch {
+			matches[i] = newJSONMatch(m)
+		}
+		return json.Marshal(notifyJSONPayload{
+			Schema:  jsonSchemaVersion,
+			Type:    "notification",
+			Pattern: o.pattern,
+			Matches: matches,
+		})
+	}
+}
+
+// slackEscaper escapes the characters Slack reads as links, mentions and
+// entities, even inside code spans.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// summarize renders a batch as chat markdown, cut to limit bytes when limit > 0.
+// Everything taken from the scanned files goes into code spans, so none of
+// it is formatted.
+func (o *notifyOutput) summarize(batch []flagrep.Match, limit int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "flagrep found %d match(es) for %s", len(batch), codeSpan(o.pattern))
+	for i, m := range batch {
+		line := fmt.Sprintf("\n- %s (%s): %s", codeSpan(m.File), decoderChain(m.Decoders), codeSpan(highlight(m, "")))
+		if limit > 0 && b.Len()+len(line) > limit-32 {
+			fmt.Fprintf(&b, "\n... and %d more", len(batch)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// codeSpan puts s in a chat code span. Slack has no longer fences, so
+// backticks become quotes rather than end the span.
+func codeSpan(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// bump when the shape of the JSON records changes
+const jsonSchemaVersion = 1
+
+// newOutput returns the Output for the given -format value. color is the SGR
+// sequence used to highlight matches in text output, "" for none.
+func newOutput(format string, w io.Writer, color string) (flagrep.Output, error) {
+	switch format {
+	case "text", "":
+		return newTextOutput(w, color), nil
+	case "json":
+		return newJSONOutput(w), nil
+	case "csv":
+		return newCSVOutput(w), nil
+	case "sarif":
+		return newSARIFOutput(w), nil
+	case "markdown", "md":
+		return newMarkdownOutput(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// multiOutput sends every event to several outputs.
+type multiOutput []flagrep.Output
+
+func (o multiOutput) Begin(info flagrep.ScanInfo) {
+	for _, out := range o {
+		out.Begin(info)
+	}
+}
+
+func (o multiOutput) Match(m flagrep.Match) {
+	for _, out := range o {
+		out.Match(m)
+	}
+}
+
+func (o multiOutput) Truncated(file string, decoders []string) {
+	for _, out := range o {
+		out.Truncated(file, decoders)
+	}
+}
+
+func (o multiOutput) End(summary flagrep.ScanSummary) {
+	for _, out := range o {
+		out.End(summary)
+	}
+}
+
+func decoderChain(decoders []string) string {
+	if len(decoders) == 0 {
+		return "None"
+	}
+	return strings.Join(decoders, " -> ")
+}
+
+// allChains lists the primary decoder chain followed by its alternatives.
+func allChains(m flagrep.Match) []string {
+	chains := []string{decoderChain(m.Decoders)}
+	for _, alt := range m.Alternatives {
+		chains = append(chains, decoderChain(alt))
+	}
+	return chains
+}
+
+func escapeControl(s string) string {
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return strings.ReplaceAll(s, "\r", "\\r")
+}
+
+// textOutput is the classic "[MATCH] File: ..." output.
+type textOutput struct {
+	mu    sync.Mutex
+	w     io.Writer
+	color string
+}
+
+func newTextOutput(w io.Writer, color string) *textOutput {
+	return &textOutput{w: w, color: color}
+}
+
+func (o *textOutput) Begin(info flagrep.ScanInfo) {
+	// just in case
+	fmt.Fprintln(o.w, "*Expect false positives")
+}
+
+// matchFile names the file of m, followed by the record and the field or
+// registry value it was found in.
+func matchFile(m flagrep.Match) string {
+	name := m.File
+	if m.Record > 0 {
+		name += " record " + strconv.Itoa(m.Record)
+	}
+	if m.Field != "" {
+		name += " " + m.Field
+	}
+	return name
+}
+
+// highlight renders the match with its context on a single line.
+func highlight(m flagrep.Match, color string) string {
+	text := escapeControl(m.Text)
+	if color != "" {
+		text = "\033[" + color + "m" + text + "\033[0m"
+	}
+	return escapeControl(m.Before) + text + escapeControl(m.After)
+}
+
+func (o *textOutput) Match(m flagrep.Match) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case m.Rule == flagrep.RulePrivateKey:
+		// the one finding nobody should scroll past
+		fmt.Fprintf(o.w, "[PRIVATE KEY] File: %s | Decoders: %s | %s\n", matchFile(m), strings.Join(allChains(m), ", "), m.Detail)
+		return
+	case m.Detail != "":
+		fmt.Fprintf(o.w, "[%s] File: %s | Decoders: %s | %s\n", strings.ToUpper(m.Rule), matchFile(m), strings.Join(allChains(m), ", "), m.Detail)
+		return
+	case m.Rule != "":
+		fmt.Fprintf(o.w, "[MATCH] File: %s | Rule: %s | Content: ...%s...\n", matchFile(m), m.Rule, highlight(m, o.color))
+		return
+	}
+	fmt.Fprintf(o.w, "[MATCH] File: %s | Decoders: %s | Content: ...%s...\n", matchFile(m), strings.Join(allChains(m), ", "), highlight(m, o.color))
+	for _, step := range m.Steps {
+		name := "input"
+		if step.Decoder != "" {
+			name = "-> " + step.Decoder
+		}
+		fmt.Fprintf(o.w, "    %-22s %s\n", name+":", step.Snippet)
+	}
+}
+
+func (o *textOutput) Truncated(file string, decoders []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.w, "[MATCH] File: %s | Decoders: %s | ... and more matches ...\n", file, decoderChain(decoders))
+}
+
+func (o *textOutput) End(summary flagrep.ScanSummary) {
+	if summary.StoppedBy != "" {
+		fmt.Fprintln(o.w, truncatedNotice(summary))
+	}
+}
+
+// truncatedNotice tells that a -max-results or -max-time limit cut the scan
+// short, so a clean-looking result is not mistaken for a complete one.
+func truncatedNotice(summary flagrep.ScanSummary) string {
+	return fmt.Sprintf("[TRUNCATED] Scan stopped by -%s after %d file(s) and %d match(es)", summary.StoppedBy, summary.FilesScanned, summary.Matches)
+}
+
+// headingOutput prints every file once as a header followed by its matches.
+// Hits that different decoder chains produced at the same place in the file
+// are shown once, listing all chains.
+type headingOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	color   string
+	byFile  map[string][]*headingEntry
+	ordered []string
+}
+
+type headingEntry struct {
+	match  flagrep.Match
+	chains []string
+}
+
+func newHeadingOutput(w io.Writer, color string) *headingOutput {
+	return &headingOutput{w: w, color: color, byFile: make(map[string][]*headingEntry)}
+}
+
+func (o *headingOutput) Begin(info flagrep.ScanInfo) {
+	// just in case
+	fmt.Fprintln(o.w, "*Expect false positives")
+}
+
+func (o *headingOutput) Match(m flagrep.Match) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, seen := o.byFile[m.File]
+	if !seen {
+		o.ordered = append(o.ordered, m.File)
+	}
+	for _, e := range entries {
+		// the same place in the file, whatever the decoders made of the
+		// context around it
+		if e.match.Text == m.Text && e.match.SourceOffset == m.SourceOffset &&
+			e.match.Record == m.Record && e.match.Field == m.Field {
+			for _, chain := range allChains(m) {
+				if !slices.Contains(e.chains, chain) {
+					e.chains = append(e.chains, chain)
+				}
+			}
+			return
+		}
+	}
+	o.byFile[m.File] = append(entries, &headingEntry{match: m, chains: allChains(m)})
+}
+
+func (o *headingOutput) Truncated(file string, decoders []string) {}
+
+func (o *headingOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	files := slices.Clone(o.ordered)
+	slices.Sort(files)
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(o.w)
+		}
+		entries := o.byFile[file]
+		fmt.Fprintf(o.w, "%s (%d)\n", file, len(entries))
+		for _, e := range entries {
+			fmt.Fprintf(o.w, "  Decoders: %s | Content: ...%s...\n", strings.Join(e.chains, ", "), highlight(e.match, o.color))
+		}
+	}
+	if summary.StoppedBy != "" {
+		if len(files) > 0 {
+			fmt.Fprintln(o.w)
+		}
+		fmt.Fprintln(o.w, truncatedNotice(summary))
+	}
+}
+
+// jsonOutput emits a JSON Lines stream: one scan_start record, one record
+// per match and a closing scan_summary record.
+type jsonOutput struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newJSONOutput(w io.Writer) *jsonOutput {
+	return &jsonOutput{enc: json.NewEncoder(w)}
+}
+
+type jsonScanOptions struct {
+	Recursive     bool `json:"recursive"`
+	CaseSensitive bool `json:"case_sensitive"`
+	Workers       int  `json:"workers"`
+	Depth         int  `json:"depth"`
+	ContextBefore int  `json:"context_before"`
+	ContextAfter  int  `json:"context_after"`
+}
+
+type jsonScanStart struct {
+	Schema    int             `json:"schema"`
+	Type      string          `json:"type"`
+	Version   string          `json:"version"`
+	Timestamp time.Time       `json:"timestamp"`
+	Pattern   string          `json:"pattern"`
+	Preset    string          `json:"preset,omitempty"`
+	Paths     []string        `json:"paths"`
+	Options   jsonScanOptions `json:"options"`
+}
+
+type jsonMatch struct {
+	Schema      int      `json:"schema"`
+	Type        string   `json:"type"`
+	File        string   `json:"file"`
+	FileSHA256  string   `json:"file_sha256"`
+	Fingerprint string   `json:"fingerprint"`
+	Decoders    []string `json:"decoders"`
+	// omitted when no other chain produced the same content
+	Alternatives [][]string     `json:"alternative_decoders,omitempty"`
+	Pattern      string         `json:"pattern"`
+	Preset       string         `json:"preset,omitempty"`
+	Rule         string         `json:"rule,omitempty"`
+	Detail       string         `json:"detail,omitempty"`
+	Match        string         `json:"match"`
+	Offset       int            `json:"offset"`
+	Line         int            `json:"line,omitempty"`
+	Record       int            `json:"record,omitempty"`
+	Field        string         `json:"field,omitempty"`
+	Before       string         `json:"before"`
+	After        string         `json:"after"`
+	Confidence   float64        `json:"confidence"`
+	Steps        []flagrep.Step `json:"steps,omitempty"`
+}
+
+type jsonScanSummary struct {
+	Schema       int    `json:"schema"`
+	Type         string `json:"type"`
+	FilesScanned int64  `json:"files_scanned"`
+	Matches      int64  `json:"matches"`
+	DurationMS   int64  `json:"duration_ms"`
+	Truncated    bool   `json:"truncated"`
+	StoppedBy    string `json:"stopped_by,omitempty"`
+}
+
+func (o *jsonOutput) write(v any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.enc.Encode(v)
+}
+
+func (o *jsonOutput) Begin(info flagrep.ScanInfo) {
+	paths := info.Paths
+	if paths == nil {
+		paths = []string{}
+	}
+	o.write(jsonScanStart{
+		Schema:    jsonSchemaVersion,
+		Type:      "scan_start",
+		Version:   version,
+		Timestamp: info.Started.UTC(),
+		Pattern:   info.Pattern,
+		Preset:    info.Preset,
+		Paths:     paths,
+		Options: jsonScanOptions{
+			Recursive:     info.Recursive,
+			CaseSensitive: info.CaseSensitive,
+			Workers:       info.Workers,
+			Depth:         info.Depth,
+			ContextBefore: info.ContextBefore,
+			ContextAfter:  info.ContextAfter,
+		},
+	})
+}
+
+func newJSONMatch(m flagrep.Match) jsonMatch {
+	decoders := m.Decoders
+	if decoders == nil {
+		decoders = []string{}
+	}
+	return jsonMatch{
+		Schema:       jsonSchemaVersion,
+		Type:         "match",
+		File:         m.File,
+		FileSHA256:   m.FileHash,
+		Fingerprint:  flagrep.Fingerprint(m),
+		Decoders:     decoders,
+		Alternatives: m.Alternatives,
+		Pattern:      m.Pattern,
+		Preset:       m.Preset,
+		Rule:         m.Rule,
+		Detail:       m.Detail,
+		Match:        m.Text,
+		Offset:       m.Offset,
+		Line:         m.Line,
+		Record:       m.Record,
+		Field:        m.Field,
+		Before:       m.Before,
+		After:        m.After,
+		Confidence:   m.Confidence,
+		Steps:        m.Steps,
+	}
+}
+
+func (o *jsonOutput) Match(m flagrep.Match) {
+	o.write(newJSONMatch(m))
+}
+
+func (o *jsonOutput) Truncated(file string, decoders []string) {}
+
+func (o *jsonOutput) End(summary flagrep.ScanSummary) {
+	o.write(jsonScanSummary{
+		Schema:       jsonSchemaVersion,
+		Type:         "scan_summary",
+		FilesScanned: summary.FilesScanned,
+		Matches:      summary.Matches,
+		DurationMS:   summary.Duration.Milliseconds(),
+		Truncated:    summary.StoppedBy != "",
+		StoppedBy:    summary.StoppedBy,
+	})
+}
+
+// csvOutput writes one row per match, with a header row first.
+type csvOutput struct {
+	mu sync.Mutex
+	w  *csv.Writer
+}
+
+func newCSVOutput(w io.Writer) *csvOutput {
+	return &csvOutput{w: csv.NewWriter(w)}
+}
+
+func (o *csvOutput) Begin(info flagrep.ScanInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Write([]string{"file", "decoders", "match", "context", "offset", "alternative_decoders"})
+}
+
+func (o *csvOutput) Match(m flagrep.Match) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Write([]string{
+		m.File,
+		strings.Join(m.Decoders, " -> "),
+		m.Text,
+		m.Before + m.Text + m.After,
+		strconv.Itoa(m.Offset),
+		strings.Join(allChains(m)[1:], ", "),
+	})
+}
+
+func (o *csvOutput) Truncated(file string, decoders []string) {}
+
+func (o *csvOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Flush()
+}
@@ -0,0 +1,123 @@
+package flagrep
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+)
+
+// a decoder that produced this many states without ever being on the chain
+// of a match is skipped below the first decoding level
+const pruneMinAttempts = 500
+
+// DecoderStats records how productive each decoder is: how many new states
+// it produced and how many of those led to a match. Searchers sharing it
+// try productive decoders first. It can be saved and loaded to carry the
+// experience over to later runs.
+type DecoderStats struct {
+	mu       sync.Mutex
+	decoders map[string]decoderCount
+	// skip fruitless decoders at depth 2 and below, see pruneMinAttempts
+	Prune bool
+}
+
+type decoderCount struct {
+	Attempts int64 `json:"attempts"`
+	Hits     int64 `json:"hits"`
+}
+
+type decoderStatsFile struct {
+	Version  int                     `json:"version"`
+	Decoders map[string]decoderCount `json:"decoders"`
+}
+
+func NewDecoderStats() *DecoderStats {
+	return &DecoderStats{decoders: make(map[string]decoderCount)}
+}
+
+// LoadDecoderStats reads stats saved by Save. A missing file gives empty
+// stats, so the first run can create it.
+func LoadDecoderStats(path string) (*DecoderStats, error) {
+	stats := NewDecoderStats()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file decoderStatsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for name, c := range file.Decoders {
+		stats.decoders[name] = c
+	}
+	return stats, nil
+}
+
+// Save writes the stats as JSON.
+func (d *DecoderStats) Save(path string) error {
+	d.mu.Lock()
+	data, err := json.MarshalIndent(decoderStatsFile{Version: 1, Decoders: d.decoders}, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// order sorts names by hit rate, most productive first. Decoders without
+// stats keep their place among the ones with a zero rate.
+func (d *DecoderStats) order(names []string) []string {
+	d.mu.Lock()
+	rates := make(map[string]float64, len(names))
+	for _, name := range names {
+		c := d.decoders[name]
+		rates[name] = float64(c.Hits) / float64(c.Attempts+1)
+	}
+	d.mu.Unlock()
+
+	ordered := slices.Clone(names)
+	slices.SortStableFunc(ordered, func(a, b string) int {
+		switch {
+		case rates[a] > rates[b]:
+			return -1
+		case rates[a] < rates[b]:
+			return 1
+		}
+		return 0
+	})
+	return ordered
+}
+
+// fruitless lists the decoders to prune, nil unless Prune is set.
+func (d *DecoderStats) fruitless() map[string]bool {
+	if !d.Prune {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	skip := make(map[string]bool)
+	for name, c := range d.decoders {
+		if c.Hits == 0 && c.Attempts >= pruneMinAttempts {
+			skip[name] = true
+		}
+	}
+	return skip
+}
+
+// add merges the counts of one search.
+func (d *DecoderStats) add(counts map[string]decoderCount) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for name, c := range counts {
+		total := d.decoders[name]
+		total.Attempts += c.Attempts
+		total.Hits += c.Hits
+		d.decoders[name] = total
+	}
+}
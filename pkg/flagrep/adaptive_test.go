@@ -0,0 +1,66 @@
+package flagrep
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDecoderStats(t *testing.T) {
+	stats := NewDecoderStats()
+	searcher := NewSearcher(nil, "secret", false, true, 1, 1, 5, 5, false)
+	searcher.Output = &collectOutput{}
+	searcher.Stats = stats
+	searcher.searchBFS(context.Background(), base64.StdEncoding.EncodeToString([]byte("the secret")), "input")
+
+	if c := stats.decoders["base64"]; c.Attempts != 1 || c.Hits != 1 {
+		t.Errorf("base64 counted %+v, want 1 attempt and 1 hit", c)
+	}
+	if c := stats.decoders["reverse"]; c.Attempts != 1 || c.Hits != 0 {
+		t.Errorf("reverse counted %+v, want 1 attempt and no hit", c)
+	}
+	order := stats.order(slices.Sorted(maps.Keys(searcher.Decoders)))
+	if order[0] != "base64" && order[0] != "base64_url" {
+		t.Errorf("productive decoders not first: %v", order)
+	}
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := stats.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadDecoderStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.decoders["base64"] != stats.decoders["base64"] {
+		t.Errorf("round trip lost counts: %+v", loaded.decoders)
+	}
+	if _, err := LoadDecoderStats(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("missing stats file: %v", err)
+	}
+}
+
+func TestDecoderStatsPrune(t *testing.T) {
+	stats := NewDecoderStats()
+	stats.Prune = true
+	stats.add(map[string]decoderCount{"rot13": {Attempts: pruneMinAttempts}})
+
+	search := func(chain ...string) int {
+		searcher := NewSearcher(nil, "flag{x}", false, true, 1, 2, 0, 0, false)
+		out := &collectOutput{}
+		searcher.Output = out
+		searcher.Stats = stats
+		encoded, _ := EncodeChain("flag{x}", chain)
+		searcher.searchBFS(context.Background(), encoded, "input")
+		return len(out.matches)
+	}
+	if search("rot13", "hex_without_spaces") != 0 {
+		t.Error("fruitless rot13 tried at depth 2")
+	}
+	if search("rot13") == 0 {
+		t.Error("fruitless rot13 pruned at depth 1")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package flagrep
+
+// alternateStreams returns nil, alternate data streams are NTFS on Windows.
+func alternateStreams(path string) []string {
+	return nil
+}
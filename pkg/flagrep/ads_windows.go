@@ -0,0 +1,53 @@
+//go:build windows
+
+package flagrep
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+// alternateStreams returns the names of the NTFS alternate data streams of
+// the file or directory at path, which a walk never sees but "path:name"
+// opens like a file.
+func alternateStreams(path string) []string {
+	if procFindFirstStreamW.Find() != nil || procFindNextStreamW.Find() != nil {
+		return nil
+	}
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil
+	}
+	var data win32FindStreamData
+	// 0 is FindStreamInfoStandard
+	h, _, _ := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return nil
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+
+	var names []string
+	for {
+		// ":name:$DATA"; "::$DATA" is the file's own content
+		name := syscall.UTF16ToString(data.StreamName[:])
+		if stream, ok := strings.CutSuffix(strings.TrimPrefix(name, ":"), ":$DATA"); ok && stream != "" {
+			names = append(names, stream)
+		}
+		if ok, _, _ := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); ok == 0 {
+			return names
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package flagrep
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAlternateStreams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "innocent.txt")
+	if err := os.WriteFile(path, []byte("nothing here"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+":hidden", []byte("flag{ads}"), 0o644); err != nil {
+		t.Skipf("no alternate data streams on this file system: %v", err)
+	}
+
+	if got := alternateStreams(path); !slices.Equal(got, []string{"hidden"}) {
+		t.Errorf("streams %q, want [hidden]", got)
+	}
+	var visited []string
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	searcher.walkFiles(t.Context(), filepath.Dir(path), func(p string) { visited = append(visited, p) }, func(string, string) {})
+	if !slices.Contains(visited, path+":hidden") {
+		t.Errorf("walk visited %q, not the stream", visited)
+	}
+}
@@ -0,0 +1,152 @@
+package flagrep
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// n-grams are counted in this much of the file, the map grows with the input
+const ngramLimit = 1 << 20
+
+// Analysis is a frequency-analysis report of a file.
+type Analysis struct {
+	Path      string      `json:"path"`
+	Size      int         `json:"size"`
+	Magic     string      `json:"magic"`
+	Class     string      `json:"class"`
+	Entropy   float64     `json:"entropy"`
+	Printable float64     `json:"printable_ratio"`
+	Histogram [256]int    `json:"histogram"`
+	BlockSize int         `json:"block_size"`
+	Curve     []float64   `json:"entropy_curve"`
+	Header    *FileHeader `json:"header,omitempty"`
+	NGrams    []NGram     `json:"ngrams"`
+}
+
+// NGram is a byte sequence repeated in the file.
+type NGram struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// Analyze builds the report for content read from path. Entropy is sampled in
+// blocks of blockSize bytes, 0 picks a size giving about 64 blocks.
+func Analyze(path string, content []byte, blockSize, topNGrams int) *Analysis {
+	s := string(content)
+	a := &Analysis{
+		Path:      path,
+		Size:      len(content),
+		Magic:     http.DetectContentType(content),
+		Class:     classifyBlob(s[:min(len(s), 64<<10)]).String(),
+		Entropy:   round2(byteEntropy(s)),
+		Printable: round2(printableRatio(s)),
+	}
+	for _, b := range content {
+		a.Histogram[b]++
+	}
+
+	if blockSize <= 0 {
+		blockSize = max(256, (len(content)+63)/64)
+	}
+	a.BlockSize = blockSize
+	a.Curve = []float64{}
+	for off := 0; off < len(s); off += blockSize {
+		a.Curve = append(a.Curve, round2(byteEntropy(s[off:min(off+blockSize, len(s))])))
+	}
+
+	if h, err := ParseFileHeader(path); err == nil {
+		a.Header = h
+		// the sniffer only knows web formats
+		if a.Magic == "application/octet-stream" {
+			a.Magic = h.Format + " " + strings.ToLower(h.Type)
+		}
+	}
+
+	a.NGrams = []NGram{}
+	for _, n := range []int{4, 8} {
+		a.NGrams = append(a.NGrams, repeatedNGrams(s[:min(len(s), ngramLimit)], n, topNGrams)...)
+	}
+	return a
+}
+
+// repeatedNGrams returns the top most frequent n-byte sequences occurring
+// at least twice, skipping runs of a single byte like zero padding.
+func repeatedNGrams(s string, n, top int) []NGram {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(s); i++ {
+		gram := s[i : i+n]
+		if strings.Count(gram, gram[:1]) == n {
+			continue
+		}
+		counts[gram]++
+	}
+
+	var grams []NGram
+	for text, count := range counts {
+		if count > 1 {
+			grams = append(grams, NGram{Text: text, Count: count})
+		}
+	}
+	slices.SortFunc(grams, func(a, b NGram) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Text, b.Text)
+	})
+	return grams[:min(len(grams), top)]
+}
+
+// sparkline renders entropy values (0 to 8 bits) one character each.
+func sparkline(values []float64) string {
+	const levels = " .:-=+*#%@"
+	var b strings.Builder
+	for _, v := range values {
+		i := int(v/8*float64(len(levels)-1) + 0.5)
+		b.WriteByte(levels[min(max(i, 0), len(levels)-1)])
+	}
+	return b.String()
+}
+
+// FormatAnalysis renders the report for the terminal.
+func FormatAnalysis(a *Analysis) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", a.Path)
+	fmt.Fprintf(&b, "  Size:      %d bytes\n", a.Size)
+	fmt.Fprintf(&b, "  Magic:     %s\n", a.Magic)
+	fmt.Fprintf(&b, "  Class:     %s\n", a.Class)
+	fmt.Fprintf(&b, "  Entropy:   %.2f bits/byte\n", a.Entropy)
+	fmt.Fprintf(&b, "  Printable: %.0f%%\n", a.Printable*100)
+	if a.Header != nil {
+		fmt.Fprintf(&b, "  Header:    %s %s (%d-bit) %s, %d sections\n", a.Header.Format, a.Header.Arch, a.Header.Bits, a.Header.Type, len(a.Header.Sections))
+	}
+
+	fmt.Fprintf(&b, "  Entropy curve (%d-byte blocks, 0-8 bits):\n", a.BlockSize)
+	curve := sparkline(a.Curve)
+	for len(curve) > 64 {
+		fmt.Fprintf(&b, "    |%s|\n", curve[:64])
+		curve = curve[64:]
+	}
+	fmt.Fprintf(&b, "    |%s|\n", curve)
+
+	// 16 buckets of 16 byte values each
+	fmt.Fprintf(&b, "  Byte histogram:\n")
+	var buckets [16]int
+	peak := 1
+	for i, n := range a.Histogram {
+		buckets[i/16] += n
+		peak = max(peak, buckets[i/16])
+	}
+	for i, n := range buckets {
+		fmt.Fprintf(&b, "    %02x-%02x %-40s %d\n", i*16, i*16+15, strings.Repeat("#", n*40/peak), n)
+	}
+
+	if len(a.NGrams) > 0 {
+		fmt.Fprintf(&b, "  Repeated n-grams:\n")
+		for _, g := range a.NGrams {
+			fmt.Fprintf(&b, "    %-20q %d\n", g.Text, g.Count)
+		}
+	}
+	return b.String()
+}
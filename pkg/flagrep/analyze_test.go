@@ -0,0 +1,27 @@
+package flagrep
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	content := []byte(strings.Repeat("flag{abc} ", 50))
+	a := Analyze("flags.txt", content, 100, 3)
+
+	if a.Size != len(content) || a.Printable != 1 {
+		t.Errorf("size %d, printable %v", a.Size, a.Printable)
+	}
+	if !strings.HasPrefix(a.Magic, "text/plain") {
+		t.Errorf("magic = %q", a.Magic)
+	}
+	if len(a.Curve) != 5 {
+		t.Errorf("expected 5 entropy blocks, got %v", a.Curve)
+	}
+	if a.Histogram['f'] != 50 {
+		t.Errorf("histogram['f'] = %d, want 50", a.Histogram['f'])
+	}
+	if len(a.NGrams) != 6 || a.NGrams[0].Count < 49 {
+		t.Errorf("unexpected n-grams: %+v", a.NGrams)
+	}
+}
@@ -0,0 +1,219 @@
+package flagrep
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path"
+	"strings"
+)
+
+// apkComponent is a part of an Android package searched on its own.
+type apkComponent struct {
+	path string // e.g. classes.dex or lib/arm64-v8a/libnative.so
+	// the entries of its string tables, or all of a native library
+	texts []string
+}
+
+// components larger than this when decompressed are left out, a zip bomb
+// would otherwise take all memory
+const maxAPKComponent = 256 << 20
+
+// resource chunk types, see ResourceTypes.h in the Android sources
+const (
+	resStringPoolType = 0x0001
+	resTableType      = 0x0002
+	resXMLType        = 0x0003
+	resPackageType    = 0x0200
+	resUTF8Flag       = 0x100
+)
+
+// apkComponents opens an Android package and returns the strings of its
+// DEX files, resources.arsc and binary AndroidManifest.xml, and its native
+// libraries. ok is false for anything that is not an APK. Secrets in an
+// app live in these string tables, compressed inside the zip where the
+// regular scan cannot see them.
+func apkComponents(content []byte) (components []apkComponent, ok bool) {
+	if !bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		return nil, false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, false
+	}
+	isAPK := false
+	for _, f := range zr.File {
+		if f.Name == "AndroidManifest.xml" {
+			isAPK = true
+		}
+	}
+	if !isAPK {
+		return nil, false
+	}
+
+	for _, f := range zr.File {
+		dir, base := path.Split(f.Name)
+		var parse func([]byte) []string
+		switch {
+		case dir == "" && strings.HasPrefix(base, "classes") && strings.HasSuffix(base, ".dex"):
+			parse = dexStrings
+		case f.Name == "resources.arsc" || f.Name == "AndroidManifest.xml":
+			parse = resStrings
+		case strings.HasPrefix(dir, "lib/") && strings.HasSuffix(base, ".so"):
+			parse = func(data []byte) []string { return []string{string(data)} }
+		default:
+			continue
+		}
+		if f.UncompressedSize64 > maxAPKComponent {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxAPKComponent))
+		rc.Close()
+		if err != nil {
+			continue
+		}
+		if texts := parse(data); len(texts) > 0 {
+			components = append(components, apkComponent{path: f.Name, texts: texts})
+		}
+	}
+	return components, true
+}
+
+// dexStrings returns the string table of a Dalvik executable: class and
+// method names, and every string constant of the code.
+func dexStrings(data []byte) []string {
+	if len(data) < 0x70 || !bytes.HasPrefix(data, []byte("dex\n")) {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint32(data[0x38:]))
+	idsOff := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	if idsOff > len(data) || count > (len(data)-idsOff)/4 {
+		return nil
+	}
+	var result []string
+	for i := range count {
+		off := int(binary.LittleEndian.Uint32(data[idsOff+4*i:]))
+		if off >= len(data) {
+			continue
+		}
+		// the length in UTF-16 units, as a ULEB128, then MUTF-8 up to a NUL
+		for off < len(data) && data[off]&0x80 != 0 {
+			off++
+		}
+		off++
+		if off >= len(data) {
+			continue
+		}
+		end := bytes.IndexByte(data[off:], 0)
+		if end <= 0 {
+			continue
+		}
+		result = append(result, string(data[off:off+end]))
+	}
+	return result
+}
+
+// resStrings returns the strings of every string pool in a compiled
+// resource table or binary XML document.
+func resStrings(data []byte) []string {
+	var result []string
+	var walk func(start, end int)
+	walk = func(start, end int) {
+		for pos := start; pos+8 <= end; {
+			typ := binary.LittleEndian.Uint16(data[pos:])
+			headerSize := int(binary.LittleEndian.Uint16(data[pos+2:]))
+			size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+			if size < 8 || headerSize < 8 || headerSize > size || size > end-pos {
+				return
+			}
+			switch typ {
+			case resStringPoolType:
+				result = append(result, resStringPool(data[pos:pos+size], headerSize)...)
+			case resTableType, resXMLType, resPackageType:
+				walk(pos+headerSize, pos+size)
+			}
+			pos += size
+		}
+	}
+	walk(0, len(data))
+	return result
+}
+
+// resStringPool decodes a ResStringPool chunk, whose strings are either
+// UTF-8 or UTF-16 and are preceded by their lengths.
+func resStringPool(chunk []byte, headerSize int) []string {
+	if headerSize < 28 {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	utf8 := binary.LittleEndian.Uint32(chunk[16:])&resUTF8Flag != 0
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	if count > (len(chunk)-headerSize)/4 || stringsStart > len(chunk) {
+		return nil
+	}
+
+	var result []string
+	for i := range count {
+		pos := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+4*i:]))
+		var s string
+		if utf8 {
+			s = resUTF8String(chunk, pos)
+		} else {
+			s = resUTF16String(chunk, pos)
+		}
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func resUTF8String(chunk []byte, pos int) string {
+	// the length in UTF-16 units, then in bytes; a set high bit makes
+	// either two bytes long
+	length := func() int {
+		if pos >= len(chunk) {
+			return -1
+		}
+		n := int(chunk[pos])
+		pos++
+		if n&0x80 != 0 {
+			if pos >= len(chunk) {
+				return -1
+			}
+			n = (n&0x7f)<<8 | int(chunk[pos])
+			pos++
+		}
+		return n
+	}
+	length()
+	n := length()
+	if n < 0 || pos+n > len(chunk) {
+		return ""
+	}
+	return string(chunk[pos : pos+n])
+}
+
+func resUTF16String(chunk []byte, pos int) string {
+	if pos+2 > len(chunk) {
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint16(chunk[pos:]))
+	pos += 2
+	if n&0x8000 != 0 {
+		if pos+2 > len(chunk) {
+			return ""
+		}
+		n = (n&0x7fff)<<16 | int(binary.LittleEndian.Uint16(chunk[pos:]))
+		pos += 2
+	}
+	if n > (len(chunk)-pos)/2 {
+		return ""
+	}
+	return decodeUTF16LE(chunk[pos : pos+2*n])
+}
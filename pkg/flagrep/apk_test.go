@@ -0,0 +1,146 @@
+package flagrep
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// testDex lays out a DEX header and string table holding strs.
+func testDex(strs ...string) []byte {
+	data := make([]byte, 0x70)
+	copy(data, "dex\n035\x00")
+	binary.LittleEndian.PutUint32(data[0x38:], uint32(len(strs)))
+	binary.LittleEndian.PutUint32(data[0x3c:], 0x70)
+	data = append(data, make([]byte, 4*len(strs))...)
+	for i, s := range strs {
+		binary.LittleEndian.PutUint32(data[0x70+4*i:], uint32(len(data)))
+		data = append(data, byte(len(s)))
+		data = append(data, s...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+// testStringPool lays out a ResStringPool chunk of UTF-8 or UTF-16 strings.
+func testStringPool(utf8 bool, strs ...string) []byte {
+	var body []byte
+	offsets := make([]byte, 4*len(strs))
+	for i, s := range strs {
+		binary.LittleEndian.PutUint32(offsets[4*i:], uint32(len(body)))
+		if utf8 {
+			body = append(body, byte(len(s)), byte(len(s)))
+			body = append(body, s...)
+			body = append(body, 0)
+		} else {
+			body = binary.LittleEndian.AppendUint16(body, uint16(len(s)))
+			body = append(body, utf16le(s)...)
+		}
+	}
+	header := make([]byte, 28)
+	binary.LittleEndian.PutUint16(header, resStringPoolType)
+	binary.LittleEndian.PutUint16(header[2:], 28)
+	binary.LittleEndian.PutUint32(header[4:], uint32(28+len(offsets)+len(body)))
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(strs)))
+	if utf8 {
+		binary.LittleEndian.PutUint32(header[16:], resUTF8Flag)
+	}
+	binary.LittleEndian.PutUint32(header[20:], uint32(28+len(offsets)))
+	return slices.Concat(header, offsets, body)
+}
+
+// testResChunk wraps children in a chunk of the given type.
+func testResChunk(typ uint16, children ...[]byte) []byte {
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint16(header, typ)
+	binary.LittleEndian.PutUint16(header[2:], 8)
+	body := slices.Concat(children...)
+	binary.LittleEndian.PutUint32(header[4:], uint32(8+len(body)))
+	return append(header, body...)
+}
+
+func TestAPKComponents(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString([]byte("flag{in_dex}"))
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{
+		"AndroidManifest.xml":          testResChunk(resXMLType, testStringPool(false, "package", "flag{manifest}")),
+		"classes.dex":                  testDex("Lcom/example/Main;", secret),
+		"resources.arsc":               testResChunk(resTableType, testStringPool(true, "flag{resource}")),
+		"lib/arm64-v8a/libnative.so":   []byte("\x7fELF flag{native}"),
+		"assets/ignored.txt":           []byte("flag{asset}"),
+		"res/drawable/icon.png":        []byte("flag{png}"),
+		"lib/arm64-v8a/notalib.txt":    []byte("flag{txt}"),
+		"META-INF/MANIFEST.MF":         []byte("Manifest-Version: 1.0"),
+		"lib/armeabi-v7a/libnative.so": []byte("flag{arm}"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 20, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), buf.Bytes(), "app.apk")
+
+	got := make(map[string]string)
+	for _, m := range out.matches {
+		// short entries stay readable in the deflated zip itself
+		if m.Field != "" {
+			got[m.Field] = m.Text + m.After
+		}
+	}
+	want := map[string]string{
+		"AndroidManifest.xml":          "flag{manifest}",
+		"classes.dex":                  "flag{in_dex}",
+		"resources.arsc":               "flag{resource}",
+		"lib/arm64-v8a/libnative.so":   "flag{native}",
+		"lib/armeabi-v7a/libnative.so": "flag{arm}",
+	}
+	for field, text := range want {
+		if got[field] != text {
+			t.Errorf("%s: got %q, want %q", field, got[field], text)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("matches in %v", got)
+	}
+
+	// a plain zip is not an APK
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	zw.Create("classes.dex")
+	zw.Close()
+	if _, ok := apkComponents(buf.Bytes()); ok {
+		t.Error("zip without a manifest taken for an APK")
+	}
+}
+
+func TestResStringsCorrupt(t *testing.T) {
+	data := testResChunk(resTableType, testStringPool(false, "abc", "flag{x}"))
+	for i := range data {
+		for _, b := range []byte{0, 0x7f, 0xff} {
+			corrupt := bytes.Clone(data)
+			corrupt[i] = b
+			resStrings(corrupt)
+			resStrings(corrupt[:i])
+		}
+	}
+	dex := testDex("abc", "flag{x}")
+	for i := range dex {
+		corrupt := bytes.Clone(dex)
+		corrupt[i] = 0xff
+		dexStrings(corrupt)
+		dexStrings(dex[:i])
+	}
+}
@@ -0,0 +1,101 @@
+package flagrep
+
+import "strings"
+
+// applicable holds cheap byte-class checks for the built-in decoders. A
+// check returning false means the decoder would certainly fail or give the
+// content back unchanged, so the BFS does not pay for running it. Decoders
+// without a check are always run.
+var applicable = map[string]func(string) bool{
+	"space_removal":      func(s string) bool { return strings.IndexByte(s, ' ') >= 0 },
+	"base64":             onlyBytes(base64StdAlphabet),
+	"base64_url":         onlyBytes(base64URLAlphabet),
+	"base32":             onlyBytes(base32Alphabet),
+	"hex_with_spaces":    hasSpacedHexPair,
+	"hex_without_spaces": hasHexRun,
+	"hex_with_prefix":    func(s string) bool { return strings.Contains(s, "0x") },
+	"rot13":              hasASCIILetter,
+	"sql_literals": func(s string) bool {
+		return indexASCIIFold(s, "0x") >= 0 || indexASCIIFold(s, "x'") >= 0 ||
+			indexASCIIFold(s, "unhex(") >= 0 || indexASCIIFold(s, "from_base64(") >= 0
+	},
+	"base64_block": func(s string) bool { return strings.IndexByte(s, '\n') >= 0 },
+	"string_escapes": func(s string) bool {
+		return strings.IndexByte(s, '\\') >= 0 && strings.ContainsAny(s, `"'`)
+	},
+	"rot47": func(s string) bool {
+		return strings.ContainsFunc(s, func(r rune) bool { return r >= '!' && r <= '~' })
+	},
+	// not that cheap, but it keeps solverRuns for texts actually solved
+	"substitution": func(s string) bool { return substitutionSample(s) != nil },
+}
+
+var (
+	base64StdAlphabet = byteSet("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=\r\n")
+	base64URLAlphabet = byteSet("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_=\r\n")
+	// the decoders run the standard, upper case alphabet
+	base32Alphabet = byteSet("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567=\r\n")
+)
+
+func byteSet(chars string) *[256]bool {
+	var set [256]bool
+	for i := 0; i < len(chars); i++ {
+		set[chars[i]] = true
+	}
+	return &set
+}
+
+// onlyBytes checks that s is not empty and every byte is in set; strict
+// decoders reject anything else.
+func onlyBytes(set *[256]bool) func(string) bool {
+	return func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if !set[s[i]] {
+				return false
+			}
+		}
+		return s != ""
+	}
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// hasHexRun looks for the 6 hex digits hex_without_spaces needs at least.
+func hasHexRun(s string) bool {
+	run := 0
+	for i := 0; i < len(s); i++ {
+		if !isHexDigit(s[i]) {
+			run = 0
+			continue
+		}
+		if run++; run == 6 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSpacedHexPair looks for the "XX XX" that starts every run
+// hex_with_spaces decodes.
+func hasSpacedHexPair(s string) bool {
+	for i := 0; i+2 < len(s); i++ {
+		switch s[i+2] {
+		case ' ', '\t', '\n', '\f', '\r':
+			if isHexDigit(s[i]) && isHexDigit(s[i+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasASCIILetter(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c >= 'a' && c <= 'z' {
+			return true
+		}
+	}
+	return false
+}
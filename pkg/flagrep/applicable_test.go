@@ -0,0 +1,71 @@
+package flagrep
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// A failed check must only ever skip work: the decoder would have failed,
+// produced nothing or returned its input.
+func TestApplicableChecksAreSafe(t *testing.T) {
+	decoders := Decoders()
+	if err := EnableSolvers(decoders, "substitution"); err != nil {
+		t.Fatal(err)
+	}
+	for name := range applicable {
+		if decoders[name] == nil {
+			t.Errorf("check for unknown decoder %s", name)
+		}
+	}
+
+	alphabets := []string{
+		"0123456789abcdef \n",
+		"0x123456789ABCDEF ",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ234567=",
+		"ABCDEFabcdef0123456789+/-_=\r\n",
+		"!@#$%^&*()[]{} \t\x00\xff",
+		"hello world flag{x}",
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 20000 {
+		alphabet := alphabets[rng.IntN(len(alphabets))]
+		b := make([]byte, rng.IntN(24))
+		for i := range b {
+			b[i] = alphabet[rng.IntN(len(alphabet))]
+		}
+		input := string(b)
+		for name, check := range applicable {
+			if check(input) {
+				continue
+			}
+			decoded, err := decoders[name](input)
+			if err == nil && decoded != "" && decoded != input {
+				t.Fatalf("%s check rejected %q, which decodes to %q", name, input, decoded)
+			}
+		}
+	}
+}
+
+func TestApplicableChecks(t *testing.T) {
+	for _, c := range []struct {
+		decoder, input string
+		want           bool
+	}{
+		{"base64", "ZmxhZ3t4fQ==", true},
+		{"base64", "not base64!", false},
+		{"base64_url", "Zm-_", true},
+		{"base32", "MZWGCZ33PB6Q====", true},
+		{"base32", "mzwgcz33", false},
+		{"hex_with_spaces", "66 6c", true},
+		{"hex_with_spaces", "666c", false},
+		{"hex_without_spaces", "x666c61y", true},
+		{"hex_without_spaces", "66 6c 61", false},
+		{"hex_with_prefix", "0x66", true},
+		{"space_removal", "nospaces", false},
+		{"rot13", "1234 !?", false},
+	} {
+		if got := applicable[c.decoder](c.input); got != c.want {
+			t.Errorf("%s check of %q = %v, want %v", c.decoder, c.input, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package flagrep
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Fingerprint identifies a finding across scans by its file and text. It
+// deliberately ignores the offset, context and decoder chain, so edits
+// elsewhere in the file, other -A/-B values or another decoding depth do not
+// make an old finding look new.
+func Fingerprint(m Match) string {
+	sum := sha256.Sum256([]byte(m.File + "\x00" + m.Text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Baseline is the set of fingerprints reported by a previous scan.
+type Baseline map[string]struct{}
+
+func (b Baseline) Contains(m Match) bool {
+	_, ok := b[Fingerprint(m)]
+	return ok
+}
+
+// LoadBaseline reads the match records of a previous -json run.
+func LoadBaseline(path string) (Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	baseline := make(Baseline)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record struct {
+			Type  string `json:"type"`
+			File  string `json:"file"`
+			Match string `json:"match"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		if record.Type != "match" {
+			continue
+		}
+		m := Match{File: record.File, Text: record.Match}
+		baseline[Fingerprint(m)] = struct{}{}
+	}
+	return baseline, scanner.Err()
+}
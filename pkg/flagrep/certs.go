@@ -0,0 +1,119 @@
+package flagrep
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// kinds of certificate findings, reported in Match.Rule
+const (
+	RuleCertificate = "x509-certificate"
+	RulePrivateKey  = "private-key"
+)
+
+// certFinding is a certificate or private key found in content.
+type certFinding struct {
+	rule   string
+	offset int
+	text   string // PEM header line, or a short label for DER
+	detail string
+}
+
+// findCertificates reports PEM certificates and private keys in content, and
+// content that is itself a DER certificate, as produced by decoding a Base64
+// blob without its PEM armor.
+func findCertificates(content string) []certFinding {
+	var findings []certFinding
+
+	// a SEQUENCE with a long-form length, which every certificate is
+	if len(content) > 2 && content[0] == 0x30 && content[1] >= 0x81 && content[1] <= 0x83 {
+		if cert, err := x509.ParseCertificate([]byte(content)); err == nil {
+			findings = append(findings, certFinding{rule: RuleCertificate, text: "DER certificate", detail: describeCertificate(cert)})
+		}
+	}
+
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], "-----BEGIN ")
+		if i < 0 {
+			break
+		}
+		start := offset + i
+		block, rest := pem.Decode([]byte(content[start:]))
+		if block == nil {
+			offset = start + len("-----BEGIN ")
+			continue
+		}
+		header := "-----BEGIN " + block.Type + "-----"
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			detail := "unparsable certificate"
+			if err == nil {
+				detail = describeCertificate(cert)
+			}
+			findings = append(findings, certFinding{rule: RuleCertificate, offset: start, text: header, detail: detail})
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			findings = append(findings, certFinding{rule: RulePrivateKey, offset: start, text: header, detail: describePrivateKey(block)})
+		}
+		offset = len(content) - len(rest)
+	}
+	return findings
+}
+
+func describeCertificate(cert *x509.Certificate) string {
+	return fmt.Sprintf("subject=%q issuer=%q valid=%s..%s key=%s",
+		cert.Subject.String(), cert.Issuer.String(),
+		cert.NotBefore.UTC().Format("2006-01-02"), cert.NotAfter.UTC().Format("2006-01-02"),
+		describePublicKey(cert.PublicKey))
+}
+
+func describePublicKey(key any) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
+
+func describePrivateKey(block *pem.Block) string {
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+		return "encrypted " + strings.ToLower(block.Type)
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		// OpenSSH, PGP and other formats we only recognize by name
+		return "unencrypted " + strings.ToLower(block.Type)
+	}
+	if err != nil {
+		return "unparsable " + strings.ToLower(block.Type)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return describePublicKey(&k.PublicKey) + " unencrypted"
+	case *ecdsa.PrivateKey:
+		return describePublicKey(&k.PublicKey) + " unencrypted"
+	case ed25519.PrivateKey:
+		return "Ed25519 unencrypted"
+	default:
+		return fmt.Sprintf("%T unencrypted", key)
+	}
+}
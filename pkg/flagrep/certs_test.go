@@ -0,0 +1,57 @@
+package flagrep
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindCertificates(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "flagrep test"},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "config:\n" +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+
+	findings := findCertificates(content)
+	if len(findings) != 2 {
+		t.Fatalf("expected certificate and key, got %+v", findings)
+	}
+	if findings[0].rule != RuleCertificate || findings[0].offset != len("config:\n") {
+		t.Errorf("unexpected certificate finding: %+v", findings[0])
+	}
+	if !strings.Contains(findings[0].detail, "CN=flagrep test") || !strings.Contains(findings[0].detail, "2024-01-01..2025-01-01") {
+		t.Errorf("unexpected certificate detail: %s", findings[0].detail)
+	}
+	if findings[1].rule != RulePrivateKey || findings[1].detail != "Ed25519 unencrypted" {
+		t.Errorf("unexpected key finding: %+v", findings[1])
+	}
+
+	// a decoded DER blob without PEM armor
+	if der := findCertificates(string(der)); len(der) != 1 || der[0].rule != RuleCertificate {
+		t.Errorf("expected DER certificate, got %+v", der)
+	}
+}
@@ -0,0 +1,140 @@
+package flagrep
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Charsets maps -charset names to transcoders into UTF-8. Shift-JIS, GBK
+// and the other multi-byte legacy charsets need mapping tables the
+// standard library does not have; their ASCII parts match as they are.
+var Charsets = map[string]func([]byte) string{
+	"utf8":        func(b []byte) string { return string(b) },
+	"utf16le":     decodeUTF16LE,
+	"utf16be":     decodeUTF16BE,
+	"latin1":      decodeLatin1,
+	"windows1252": decodeWindows1252,
+}
+
+// bytes looked at by detectCharset
+const charsetSample = 4096
+
+// the 8-bit charsets, which leave ASCII as it is
+var eightBitCharsets = map[string]bool{"latin1": true, "windows1252": true}
+
+// detectCharset guesses the charset of a text file from its start: a byte
+// order mark, or the NUL bytes of mostly-ASCII UTF-16 without one. Text
+// whose non-ASCII bytes are mostly not UTF-8, and stand alone like the
+// accented letters of Western European languages, is taken for
+// Windows-1252, the usual 8-bit charset of Windows and a superset of the
+// printable ISO-8859-1. It returns "utf8" for UTF-8, including UTF-8 with a
+// stray invalid byte, for binary files, and for text in charsets it cannot
+// tell apart: Shift-JIS, GBK and the Cyrillic or Greek 8-bit charsets put
+// non-ASCII bytes in runs. Those are searched as they are, so their ASCII
+// parts still match.
+func detectCharset(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xef\xbb\xbf")):
+		return "utf8"
+	case bytes.HasPrefix(content, []byte("\xff\xfe")):
+		return "utf16le"
+	case bytes.HasPrefix(content, []byte("\xfe\xff")):
+		return "utf16be"
+	}
+
+	sample := content[:min(len(content), charsetSample)]
+	if len(sample) < 4 {
+		return "utf8"
+	}
+	var evenNUL, oddNUL, control int
+	for i, c := range sample {
+		switch {
+		case c == 0 && i%2 == 0:
+			evenNUL++
+		case c == 0:
+			oddNUL++
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r':
+			control++
+		}
+	}
+	// in UTF-16 text that is mostly ASCII, every other byte is NUL
+	pairs := len(sample) / 2
+	switch {
+	case oddNUL > pairs*3/4 && evenNUL < pairs/8:
+		return "utf16le"
+	case evenNUL > pairs*3/4 && oddNUL < pairs/8:
+		return "utf16be"
+	case evenNUL+oddNUL > 0 || control > len(sample)/100:
+		// binary
+		return "utf8"
+	}
+
+	// bytes of valid multi-byte UTF-8 characters, bytes that are not, and
+	// those of them in runs of three or more non-ASCII bytes
+	var valid, invalid, inRuns, run int
+	for i := 0; i < len(sample); {
+		c := sample[i]
+		if c < utf8.RuneSelf {
+			if run >= 3 {
+				inRuns += run
+			}
+			run = 0
+			i++
+			continue
+		}
+		// a character cut in two at the end of the sample is not an error
+		if !utf8.FullRune(sample[i:]) && len(sample) < len(content) {
+			break
+		}
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+			run++
+		} else {
+			valid += size
+		}
+		i += size
+	}
+	if run >= 3 {
+		inRuns += run
+	}
+	if invalid <= valid || inRuns*2 > invalid {
+		return "utf8"
+	}
+	return "windows1252"
+}
+
+func decodeUTF16BE(b []byte) string {
+	swapped := make([]byte, len(b)&^1)
+	for i := 0; i+1 < len(b); i += 2 {
+		swapped[i], swapped[i+1] = b[i+1], b[i]
+	}
+	return decodeUTF16LE(swapped)
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// the characters Windows-1252 puts where ISO-8859-1 has C1 controls;
+// 0x81, 0x8d, 0x8f, 0x90 and 0x9d are unassigned and kept as they are
+var windows1252C1 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+func decodeWindows1252(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		if c >= 0x80 && c < 0xa0 {
+			runes[i] = windows1252C1[c-0x80]
+		} else {
+			runes[i] = rune(c)
+		}
+	}
+	return string(runes)
+}
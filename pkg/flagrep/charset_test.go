@@ -0,0 +1,90 @@
+package flagrep
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			b = binary.BigEndian.AppendUint16(b, u)
+		} else {
+			b = binary.LittleEndian.AppendUint16(b, u)
+		}
+	}
+	return b
+}
+
+func TestDetectCharset(t *testing.T) {
+	text := strings.Repeat("password=hunter2 user=admin\r\n", 10)
+	long := strings.Repeat("é", charsetSample) // cut in the middle of a character
+	for _, c := range []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"ascii", []byte(text), "utf8"},
+		{"utf8", []byte("contraseña: " + text), "utf8"},
+		{"utf8 cut", []byte("x" + long), "utf8"},
+		{"utf8 bom", append([]byte("\xef\xbb\xbf"), text...), "utf8"},
+		{"utf16le", encodeUTF16(text, false), "utf16le"},
+		{"utf16be", encodeUTF16(text, true), "utf16be"},
+		{"utf16le bom", append([]byte("\xff\xfe"), encodeUTF16("日本語", false)...), "utf16le"},
+		{"latin1", []byte("contrase\xf1a: " + text), "windows1252"},
+		{"utf8 stray byte", []byte("bad byte \xff here\ncontraseña=hunter2 à bientôt\n" + text), "utf8"},
+		// 日本語のテキスト
+		{"shift-jis", []byte("title=\x93\xfa\x96\x7b\x8c\xea\x82\xcc\x83\x65\x83\x4c\x83\x58\x83\x67\n" + text), "utf8"},
+		{"binary", append([]byte("\x7fELF\x02\x01\x01\x00"), text...), "utf8"},
+	} {
+		if got := detectCharset(c.content); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestTranscodedSearch(t *testing.T) {
+	for _, c := range []struct {
+		content []byte
+		pattern string
+	}{
+		{encodeUTF16("log line\r\nflag{wide}\r\n", false), "flag{wide}"},
+		{append([]byte("\xfe\xff"), encodeUTF16("flag{big_endian}", true)...), "flag{big_endian}"},
+		{[]byte("user=jos\xe9 contrase\xf1a=\x93secret\x94\n"), "contraseña=“secret”"},
+		// one bad byte leaves the UTF-8 text readable
+		{[]byte("bad byte \xff here\ncontraseña=hunter2\n"), "contraseña"},
+		// found in the bytes as they are, not again in the text
+		{[]byte("user=jos\xe9 flag{ascii}\n"), "flag{ascii}"},
+	} {
+		searcher := NewSearcher(nil, c.pattern, false, true, 1, 0, 0, 0, false)
+		out := &collectOutput{}
+		searcher.Output = out
+		searcher.scan(context.Background(), c.content, "input")
+		if len(out.matches) != 1 {
+			t.Errorf("%q: %d matches of %q", c.content, len(out.matches), c.pattern)
+		}
+	}
+
+	// forced off, UTF-16 stays unreadable
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 0, 0, 0, false)
+	searcher.Charset = "utf8"
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), encodeUTF16("flag{wide}", false), "input")
+	if len(out.matches) != 0 {
+		t.Errorf("matched with -charset utf8: %+v", out.matches)
+	}
+}
+
+func TestWindows1252(t *testing.T) {
+	if got := decodeWindows1252([]byte("\x80 \x9f \xa0\xff")); got != "€ Ÿ  ÿ" {
+		t.Errorf("got %q", got)
+	}
+	if got := decodeUTF16BE(encodeUTF16("ab", true)); got != "ab" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,328 @@
+package flagrep
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// blob classes reported by classifyBlob
+const (
+	classShort      = "too short"
+	classHex        = "likely hex"
+	classBase32     = "likely base32"
+	classBase64     = "likely base64"
+	classEnglish    = "likely English text"
+	classSubstitute = "likely substituted English"
+	classText       = "likely text"
+	classXOR        = "likely single-byte XORed English"
+	classRandom     = "likely compressed/encrypted"
+	classBinary     = "likely binary"
+)
+
+// decoders worth trying first for each class
+var classDecoders = map[string][]string{
+	classHex:        {"hex_without_spaces", "hex_with_spaces", "hex_with_prefix"},
+	classBase32:     {"base32"},
+	classBase64:     {"base64", "base64_url", "base64_block"},
+	classSubstitute: {"rot13", "rot47", "substitution"},
+}
+
+// chi-squared distance below which letters are taken for English
+const englishChi2 = 150
+
+// English letter frequencies, a to z
+var englishFreq = [26]float64{
+	0.08167, 0.01492, 0.02782, 0.04253, 0.12702, 0.02228, 0.02015, 0.06094, 0.06966,
+	0.00153, 0.00772, 0.04025, 0.02406, 0.06749, 0.07507, 0.01929, 0.00095, 0.05987,
+	0.06327, 0.09056, 0.02758, 0.00978, 0.02360, 0.00150, 0.01974, 0.00074,
+}
+
+// Classification describes what a blob most likely is.
+type Classification struct {
+	Class   string
+	Entropy float64 // bits per byte
+	IC      float64 // index of coincidence of the letters
+	Chi2    float64 // chi-squared distance of the letters from English
+	XORKey  int     // key for classXOR, -1 otherwise
+}
+
+func (c Classification) String() string {
+	if c.XORKey >= 0 {
+		return fmt.Sprintf("%s (key 0x%02x)", c.Class, c.XORKey)
+	}
+	return c.Class
+}
+
+// classifyBlob guesses the encoding of data from its alphabet, byte entropy,
+// index of coincidence and letter frequencies.
+func classifyBlob(data string) Classification {
+	return classify(data, true)
+}
+
+// classify is classifyBlob, trying all 255 single-byte XOR keys only with
+// tryXOR.
+func classify(data string, tryXOR bool) Classification {
+	c := Classification{XORKey: -1}
+	compact := strings.Join(strings.Fields(data), "")
+	if len(compact) < 8 {
+		c.Class = classShort
+		return c
+	}
+	c.Entropy = byteEntropy(data)
+	c.IC, c.Chi2 = letterStats(data)
+
+	if c.Class = alphabetClass(compact); c.Class != "" {
+		return c
+	}
+
+	printable := printableRatio(data) > 0.95
+	if printable {
+		if englishScore(data) < englishChi2 {
+			c.Class = classEnglish
+			return c
+		}
+		if bestCaesarShift(data) < englishChi2 {
+			c.Class = classSubstitute
+			return c
+		}
+	}
+	// XORing with most keys keeps English printable, so try it either way
+	if tryXOR {
+		if key, chi2 := bestSingleByteXOR(data); chi2 < englishChi2 {
+			c.Class = classXOR
+			c.XORKey = key
+			return c
+		}
+	}
+	if printable {
+		c.Class = classText
+		return c
+	}
+	// short inputs cannot reach 8 bits of entropy per byte
+	if c.Entropy > 0.9*math.Log2(float64(min(len(data), 256))) {
+		c.Class = classRandom
+		return c
+	}
+	c.Class = classBinary
+	return c
+}
+
+// alphabetClass recognizes the base encodings by their alphabet, "" when s
+// is none of them. s must not contain whitespace.
+func alphabetClass(s string) string {
+	switch {
+	case onlyRunes(s, "0123456789abcdefABCDEF") && len(s)%2 == 0:
+		return classHex
+	case onlyRunes(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567=") && len(s)%8 == 0:
+		return classBase32
+	case onlyRunes(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_=") && hasMixedCase(s):
+		return classBase64
+	}
+	return ""
+}
+
+func onlyRunes(s, alphabet string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune(alphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasMixedCase(s string) bool {
+	return strings.ToLower(s) != s && strings.ToUpper(s) != s
+}
+
+func printableRatio(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	printable := 0
+	for i := 0; i < len(s); i++ {
+		if isPrintableASCII(s[i]) || s[i] == '\n' || s[i] == '\r' {
+			printable++
+		}
+	}
+	return float64(printable) / float64(len(s))
+}
+
+func byteEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		if n == 0 {
+			continue
+		}
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// letterStats returns the index of coincidence and the chi-squared distance
+// from English of the ASCII letters in s, ignoring case. Both are 0 when s
+// has fewer than two letters.
+func letterStats(s string) (ic, chi2 float64) {
+	var counts [26]int
+	total := 0
+	for i := 0; i < len(s); i++ {
+		b := s[i] | 0x20
+		if b >= 'a' && b <= 'z' {
+			counts[b-'a']++
+			total++
+		}
+	}
+	if total < 2 {
+		return 0, 0
+	}
+	for i, n := range counts {
+		ic += float64(n * (n - 1))
+		expected := englishFreq[i] * float64(total)
+		chi2 += (float64(n) - expected) * (float64(n) - expected) / expected
+	}
+	ic /= float64(total * (total - 1))
+	return ic, chi2
+}
+
+// englishScore is the chi-squared distance of s from English, or +Inf when
+// less than half of s are letters.
+func englishScore(s string) float64 {
+	letters := 0
+	for i := 0; i < len(s); i++ {
+		if b := s[i] | 0x20; b >= 'a' && b <= 'z' {
+			letters++
+		}
+	}
+	if letters < 2 || letters < len(s)/2 {
+		return math.Inf(1)
+	}
+	_, chi2 := letterStats(s)
+	return chi2
+}
+
+// bestCaesarShift returns the lowest englishScore of the 25 letter rotations
+// and ROT47 of s.
+func bestCaesarShift(s string) float64 {
+	best := math.Inf(1)
+	buf := []byte(s)
+	for shift := 1; shift < 26; shift++ {
+		for i := 0; i < len(s); i++ {
+			switch b := s[i]; {
+			case b >= 'a' && b <= 'z':
+				buf[i] = 'a' + (b-'a'+byte(shift))%26
+			case b >= 'A' && b <= 'Z':
+				buf[i] = 'A' + (b-'A'+byte(shift))%26
+			}
+		}
+		best = min(best, englishScore(string(buf)))
+	}
+	if rot, err := rot47Decoder(s); err == nil {
+		best = min(best, englishScore(rot))
+	}
+	return best
+}
+
+// bestSingleByteXOR finds the key that turns s into the most English-like
+// printable text, returning it with its chi-squared score (math.Inf when no
+// key gives printable English).
+func bestSingleByteXOR(s string) (key int, chi2 float64) {
+	key, chi2 = -1, math.Inf(1)
+	buf := make([]byte, len(s))
+	for k := 1; k < 256; k++ {
+		if k == 0x20 {
+			// only swaps case, which englishScore ignores
+			continue
+		}
+		for i := 0; i < len(s); i++ {
+			buf[i] = s[i] ^ byte(k)
+		}
+		decoded := string(buf)
+		if printableRatio(decoded) < 0.95 {
+			continue
+		}
+		if score := englishScore(decoded); score < chi2 {
+			key, chi2 = k, score
+		}
+	}
+	return key, chi2
+}
+
+// Region is a part of a file that looks encoded or encrypted.
+type Region struct {
+	Offset int
+	Length int
+	Class  Classification
+}
+
+// windows suspiciousRegions classifies at most, spread over the content,
+// and regions it returns at most
+const (
+	maxRegionWindows = 64
+	maxRegions       = 8
+)
+
+// suspiciousRegions classifies windows of content and returns runs of them
+// that look encoded or encrypted. Large content is sampled, and XOR is not
+// tried; this only points the user somewhere.
+func suspiciousRegions(content string) []Region {
+	const window = 256
+	stride := window
+	if n := len(content) / window; n > maxRegionWindows {
+		stride = (n + maxRegionWindows - 1) / maxRegionWindows * window
+	}
+	var regions []Region
+	// offset of the window that last extended a region
+	last := -1
+	for off := 0; off+window/2 <= len(content); off += stride {
+		c := classify(content[off:min(off+window, len(content))], false)
+		switch c.Class {
+		case classHex, classBase32, classBase64, classRandom, classSubstitute:
+		default:
+			continue
+		}
+		// samples in a row of the same class make one region, gaps included
+		if n := len(regions); n > 0 && last == off-stride && regions[n-1].Class.Class == c.Class {
+			last = off
+			regions[n-1].Length = min(off+window, len(content)) - regions[n-1].Offset
+			continue
+		}
+		if len(regions) == maxRegions {
+			break
+		}
+		last = off
+		regions = append(regions, Region{Offset: off, Length: min(window, len(content)-off), Class: c})
+	}
+	return regions
+}
+
+// prioritizeDecoders moves the decoders suggested by the classification of
+// content to the front of names, keeping the rest in order.
+func prioritizeDecoders(names []string, content string) []string {
+	// only the cheap checks, this runs for every state
+	const sampleSize = 1024
+	sample := content[:min(len(content), sampleSize)]
+	preferred := classDecoders[alphabetClass(strings.Join(strings.Fields(sample), ""))]
+	if len(preferred) == 0 {
+		return names
+	}
+	ordered := make([]string, 0, len(names))
+	for _, name := range preferred {
+		for _, n := range names {
+			if n == name {
+				ordered = append(ordered, n)
+			}
+		}
+	}
+	for _, n := range names {
+		if !slices.Contains(preferred, n) {
+			ordered = append(ordered, n)
+		}
+	}
+	return ordered
+}
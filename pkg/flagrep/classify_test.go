@@ -0,0 +1,89 @@
+package flagrep
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClassifyBlob(t *testing.T) {
+	english := "The quick brown fox jumps over the lazy dog while the flag hides in plain sight near the river bank."
+	xored := make([]byte, len(english))
+	for i := range english {
+		xored[i] = english[i] ^ 0x5a
+	}
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"short", "abc", classShort},
+		{"hex", hex.EncodeToString([]byte(english)), classHex},
+		{"base64", base64.StdEncoding.EncodeToString([]byte(english)), classBase64},
+		{"base32", "MZWGCZ33MJQXGZJTGJ6Q====", classBase32},
+		{"english", english, classEnglish},
+		{"rot13", mustDecode(t, rot13Decoder, english), classSubstitute},
+		{"xor", string(xored), classXOR},
+		{"random", string(random), classRandom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyBlob(tt.input); got.Class != tt.want {
+				t.Errorf("classifyBlob() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := classifyBlob(string(xored)); got.XORKey != 0x5a {
+		t.Errorf("XORKey = %#x, want 0x5a", got.XORKey)
+	}
+}
+
+func TestPrioritizeDecoders(t *testing.T) {
+	names := []string{"base32", "base64", "hex_without_spaces", "rot13"}
+	got := prioritizeDecoders(names, "666c61677b746573747d")
+	if got[0] != "hex_without_spaces" || len(got) != len(names) {
+		t.Errorf("prioritizeDecoders(hex) = %v", got)
+	}
+	if got := prioritizeDecoders(names, strings.Repeat("plain words ", 4)); !slices.Equal(got, names) {
+		t.Errorf("prioritizeDecoders(text) = %v, want unchanged", got)
+	}
+}
+
+func TestSuspiciousRegions(t *testing.T) {
+	blob := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("secret payload ", 60)))
+	content := strings.Repeat("just some ordinary text here. ", 20)
+	content = content[:512] + blob
+	regions := suspiciousRegions(content)
+	if len(regions) != 1 || regions[0].Class.Class != classBase64 || regions[0].Offset != 512 {
+		t.Fatalf("suspiciousRegions() = %+v", regions)
+	}
+
+	// big content is sampled and the regions are capped
+	var mixed strings.Builder
+	r := rand.New(rand.NewSource(1))
+	for mixed.Len() < 1<<20 {
+		b := make([]byte, 300)
+		r.Read(b)
+		mixed.WriteString(hex.EncodeToString(b))
+		mixed.WriteString(strings.Repeat("just some ordinary text here. ", 30))
+	}
+	if regions := suspiciousRegions(mixed.String()); len(regions) == 0 || len(regions) > maxRegions {
+		t.Fatalf("suspiciousRegions(1MB) returned %d regions", len(regions))
+	}
+}
+
+func mustDecode(t *testing.T, decode DecoderFunc, s string) string {
+	t.Helper()
+	out, err := decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
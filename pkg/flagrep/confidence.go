@@ -0,0 +1,94 @@
+package flagrep
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// fragments that tend to sit next to real flags and secrets
+var cribs = []string{"flag", "ctf{", "key", "pass", "secret", "token", "{", "}", "=", ":"}
+
+// confidence estimates how likely a match is to be a real finding rather than
+// an artifact of decoding, from 0 (noise) to 1 (almost certainly real).
+//
+// Every decoder in the chain costs some confidence, since deep chains are
+// where coincidental matches come from. Context that is not readable text or
+// looks random lowers the score further, while known cribs around the match
+// raise it.
+func confidence(m Match) float64 {
+	score := 1 / (1 + 0.25*float64(len(m.Decoders)))
+
+	context := m.Before + m.After
+	if context == "" {
+		return round2(score)
+	}
+
+	score *= 0.5 + 0.5*englishLikeness(context)
+	if len(context) >= 16 && normalizedEntropy(context) > 0.9 {
+		score *= 0.8
+	}
+
+	lower := strings.ToLower(context)
+	hits := 0
+	for _, crib := range cribs {
+		if strings.Contains(lower, crib) {
+			hits++
+		}
+	}
+	score += 0.05 * float64(min(hits, 4))
+
+	return round2(min(score, 1))
+}
+
+// englishLikeness is the share of runes that are letters, digits, spaces or
+// punctuation common in prose and flags, with a penalty when letters are missing entirely.
+func englishLikeness(s string) float64 {
+	total, good, letters := 0, 0, 0
+	for _, r := range s {
+		total++
+		switch {
+		case unicode.IsLetter(r) && r < unicode.MaxASCII:
+			letters++
+			good++
+		case unicode.IsDigit(r), r == ' ', r == '\n', r == '\t':
+			good++
+		case strings.ContainsRune(".,;:'\"!?-_(){}[]/=", r):
+			good++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	ratio := float64(good) / float64(total)
+	if letters == 0 {
+		ratio /= 2
+	}
+	return ratio
+}
+
+// normalizedEntropy is the Shannon entropy of the bytes in s divided by the
+// maximum possible for a string of that length, so short strings compare
+// fairly with long ones.
+func normalizedEntropy(s string) float64 {
+	if len(s) < 2 {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy / math.Log2(float64(min(len(s), 256)))
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
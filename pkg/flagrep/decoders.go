@@ -0,0 +1,443 @@
+package flagrep
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// returns decoded str
+type DecoderFunc func(string) (string, error)
+
+func Decoders() map[string]DecoderFunc {
+	return map[string]DecoderFunc{
+		"reverse":            reverseDecoder,
+		"space_removal":      spaceRemovalDecoder,
+		"base64":             base64Decoder,
+		"base64_url":         base64URLDecoder,
+		"base32":             base32Decoder,
+		"hex_with_spaces":    hexWithSpacesDecoder,
+		"hex_without_spaces": hexWithoutSpacesDecoder,
+		"hex_with_prefix":    hexWithPrefixDecoder,
+		"rot13":              rot13Decoder,
+		"rot47":              rot47Decoder,
+		"string_escapes":     stringEscapesDecoder,
+		"sql_literals":       sqlLiteralsDecoder,
+		"base64_block":       base64BlockDecoder,
+		// add yours here
+	}
+}
+
+// scratch buffers for the byte-oriented decoders; their output is copied into
+// the result string anyway
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+// pooled buffers above this size are left to the garbage collector
+const maxPooledBuf = 16 << 20
+
+func getBuf() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+func putBuf(b *[]byte) {
+	if cap(*b) > maxPooledBuf {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+// decodeBytes runs a []byte decoder, such as Encoding.Decode, on a string
+// with the input and output held in pooled buffers.
+func decodeBytes(input string, decodedLen int, decode func(dst, src []byte) (int, error)) (string, error) {
+	src, dst := getBuf(), getBuf()
+	defer putBuf(src)
+	defer putBuf(dst)
+	*src = append(*src, input...)
+	*dst = slices.Grow(*dst, decodedLen)[:decodedLen]
+	n, err := decode(*dst, *src)
+	if err != nil {
+		return "", err
+	}
+	return string((*dst)[:n]), nil
+}
+
+// "olleH" -> "Hello"
+func reverseDecoder(input string) (string, error) {
+	var result strings.Builder
+	result.Grow(len(input))
+	for len(input) > 0 {
+		// invalid bytes become U+FFFD one by one, like []rune(input)
+		r, size := utf8.DecodeLastRuneInString(input)
+		result.WriteRune(r)
+		input = input[:len(input)-size]
+	}
+	return result.String(), nil
+}
+
+// "He llo" -> "Hello"
+func spaceRemovalDecoder(input string) (string, error) {
+	return strings.ReplaceAll(input, " ", ""), nil
+}
+
+// "SGVsbG8=" -> "Hello"
+func base64Decoder(input string) (string, error) {
+	return decodeBytes(input, base64.StdEncoding.DecodedLen(len(input)), base64.StdEncoding.Decode)
+}
+
+func base64URLDecoder(input string) (string, error) {
+	return decodeBytes(input, base64.URLEncoding.DecodedLen(len(input)), base64.URLEncoding.Decode)
+}
+
+// "JBSWY3DP" -> "Hello"
+func base32Decoder(input string) (string, error) {
+	return decodeBytes(input, base32.StdEncoding.DecodedLen(len(input)), base32.StdEncoding.Decode)
+}
+
+// hexString decodes plain hex digits.
+func hexString(input string) (string, error) {
+	return decodeBytes(input, hex.DecodedLen(len(input)), hex.Decode)
+}
+
+// hex runs recognized by the hex decoders, compiled once since the decoders
+// run on every state
+var (
+	hexWithSpacesRe    = regexp.MustCompile(`\b([0-9a-fA-F]{2}(?:\s+[0-9a-fA-F]{2})+)\b`)
+	hexWithoutSpacesRe = regexp.MustCompile(`\b([0-9a-fA-F]{6,})\b`)
+	hexWithPrefixRe    = regexp.MustCompile(`\b((?:0x[0-9a-fA-F]{2}(?:\s+|$))+)\b`)
+	// 0x... and X'...' literals, UNHEX('...') and FROM_BASE64('...')
+	sqlLiteralRe = regexp.MustCompile(`(?i)\b0x([0-9a-f]{6,})\b|\bx'([0-9a-f]+)'|\bunhex\(\s*['"]([0-9a-f]+)['"]\s*\)|\bfrom_base64\(\s*['"]([a-z0-9+/=\s]+)['"]\s*\)`)
+)
+
+// "48 65 6c 6c 6f" -> "Hello"
+func hexWithSpacesDecoder(input string) (string, error) {
+	return hexWithSpacesRe.ReplaceAllStringFunc(input, func(match string) string {
+		clean := strings.ReplaceAll(match, " ", "")
+		data, err := hexString(clean)
+		if err != nil {
+			return match
+		}
+		return data
+	}), nil
+}
+
+// "48656c6c6f" -> "Hello"
+func hexWithoutSpacesDecoder(input string) (string, error) {
+	return hexWithoutSpacesRe.ReplaceAllStringFunc(input, func(match string) string {
+		data, err := hexString(match)
+		if err != nil {
+			return match
+		}
+		// we keep it if decoded content contains mostly printable chars.
+		printable := 0
+		for i := 0; i < len(data); i++ {
+			if data[i] >= 32 && data[i] <= 126 {
+				printable++
+			}
+		}
+		if float64(printable)/float64(len(data)) > 0.8 {
+			return data
+		}
+		return match
+	}), nil
+}
+
+// "0x48 0x65 0x6c 0x6c 0x6f" -> "Hello"
+func hexWithPrefixDecoder(input string) (string, error) {
+	return hexWithPrefixRe.ReplaceAllStringFunc(input, func(match string) string {
+		clean := strings.ReplaceAll(match, "0x", "")
+		clean = strings.ReplaceAll(clean, " ", "")
+		data, err := hexString(clean)
+		if err != nil {
+			return match
+		}
+		return data
+	}), nil
+}
+
+// "SELECT 0x666c6167, UNHEX('7b7d')" -> "SELECT flag, {}"
+//
+// The hex and Base64 literals of SQL dumps and injection payloads, decoded
+// in place; FROM_BASE64 ignores whitespace like MySQL does.
+func sqlLiteralsDecoder(input string) (string, error) {
+	return sqlLiteralRe.ReplaceAllStringFunc(input, func(match string) string {
+		groups := sqlLiteralRe.FindStringSubmatch(match)
+		var data string
+		var err error
+		switch {
+		case groups[1] != "":
+			data, err = hexString(groups[1])
+		case groups[2] != "":
+			data, err = hexString(groups[2])
+		case groups[3] != "":
+			data, err = hexString(groups[3])
+		default:
+			clean := strings.Join(strings.Fields(groups[4]), "")
+			data, err = base64Decoder(clean)
+		}
+		if err != nil {
+			return match
+		}
+		return data
+	}), nil
+}
+
+// "-----BEGIN CERTIFICATE-----\nSGVs\nbG8=\n-----END CERTIFICATE-----" -> "Hello"
+//
+// PEM bodies, certutil -encode output and other Base64 wrapped over lines,
+// decoded as one block each; the base64 decoder only takes content that is
+// Base64 throughout. Without BEGIN and END lines a block is two or more
+// lines of the same length, at least 16 characters, and a shorter last one.
+func base64BlockDecoder(input string) (string, error) {
+	lines := strings.SplitAfter(input, "\n")
+	var result strings.Builder
+	result.Grow(len(input))
+	for i := 0; i < len(lines); {
+		n := pemBlock(lines[i:])
+		if n == 0 {
+			n = wrappedBase64Block(lines[i:])
+		}
+		if n == 0 {
+			result.WriteString(lines[i])
+			i++
+			continue
+		}
+		block := lines[i : i+n]
+		var body strings.Builder
+		for _, line := range block {
+			if !strings.HasPrefix(line, "-----") {
+				body.WriteString(strings.TrimRight(line, "\r\n"))
+			}
+		}
+		data, err := base64Decoder(body.String())
+		if err != nil {
+			result.WriteString(lines[i])
+			i++
+			continue
+		}
+		last := block[n-1]
+		result.WriteString(data)
+		result.WriteString(last[len(strings.TrimRight(last, "\r\n")):])
+		i += n
+	}
+	return result.String(), nil
+}
+
+// pemBlock returns the number of lines from a -----BEGIN line through its
+// -----END line, or 0 if lines does not start with one with Base64 between.
+func pemBlock(lines []string) int {
+	if !strings.HasPrefix(lines[0], "-----BEGIN ") {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if strings.HasPrefix(line, "-----END ") {
+			if i == 1 {
+				return 0
+			}
+			return i + 1
+		}
+		if !isBase64Line(line) {
+			return 0
+		}
+	}
+	return 0
+}
+
+var isBase64Line = onlyBytes(base64StdAlphabet)
+
+// minBase64Line is the shortest line taken for wrapped Base64; PEM and
+// certutil wrap at 64 characters, MIME at 76.
+const minBase64Line = 16
+
+// wrappedBase64Block returns the number of lines in the Base64 block lines
+// starts with, or 0.
+func wrappedBase64Block(lines []string) int {
+	width := -1
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r\n")
+		if !isBase64Line(line) {
+			return wrappedBlockLen(i)
+		}
+		switch {
+		case width < 0 && len(line) >= minBase64Line && !strings.Contains(line, "="):
+			width = len(line)
+		case len(line) == width && !strings.Contains(line, "="):
+		case len(line) <= width && (i*width+len(line))%4 == 0:
+			return wrappedBlockLen(i + 1)
+		default:
+			return wrappedBlockLen(i)
+		}
+	}
+	return wrappedBlockLen(len(lines))
+}
+
+func wrappedBlockLen(n int) int {
+	if n < 2 {
+		return 0
+	}
+	return n
+}
+
+// The rotations only touch ASCII, and bytes of multi-byte UTF-8 sequences
+// are never ASCII, so they work byte by byte.
+
+// "Uryyb" -> "Hello"
+func rot13Decoder(input string) (string, error) {
+	var result strings.Builder
+	result.Grow(len(input))
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			c = 'A' + (c-'A'+13)%26
+		}
+		result.WriteByte(c)
+	}
+	return result.String(), nil
+}
+
+// "w6==@" -> "Hello"
+func rot47Decoder(input string) (string, error) {
+	var result strings.Builder
+	result.Grow(len(input))
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if c >= '!' && c <= '~' {
+			c = 33 + (c+14)%94
+		}
+		result.WriteByte(c)
+	}
+	return result.String(), nil
+}
+
+// `key = "fl\x61g\u007b"` -> "key = flag{"
+//
+// Quoted JSON, C, JavaScript and Python string literals on a line that
+// contain escapes are replaced by their unescaped content; text outside
+// them and literals without escapes are kept as they are.
+func stringEscapesDecoder(input string) (string, error) {
+	var result strings.Builder
+	result.Grow(len(input))
+	for i := 0; i < len(input); {
+		q := input[i]
+		if q != '"' && q != '\'' {
+			result.WriteByte(q)
+			i++
+			continue
+		}
+		end := closingQuote(input, i+1, q)
+		if end < 0 || strings.IndexByte(input[i+1:end], '\\') < 0 {
+			result.WriteByte(q)
+			i++
+			continue
+		}
+		unescapeString(&result, input[i+1:end])
+		i = end + 1
+	}
+	return result.String(), nil
+}
+
+// closingQuote returns the index of the quote ending a literal that starts
+// at start, or -1 if the line ends first.
+func closingQuote(s string, start int, q byte) int {
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case q:
+			return i
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+var simpleEscapes = map[byte]byte{
+	'n': '\n', 't': '\t', 'r': '\r', 'b': '\b', 'f': '\f', 'v': '\v', 'a': '\a',
+	'\\': '\\', '"': '"', '\'': '\'', '/': '/', '?': '?',
+}
+
+// unescapeString writes the body of a string literal with its escapes
+// resolved: the single characters above, \xHH and octal \NNN bytes, and
+// \uHHHH (with surrogate pairs) and \UHHHHHHHH characters. Unknown or
+// malformed escapes are kept as written.
+func unescapeString(w *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			w.WriteByte(s[i])
+			continue
+		}
+		c := s[i+1]
+		if b, ok := simpleEscapes[c]; ok {
+			w.WriteByte(b)
+			i++
+			continue
+		}
+		switch {
+		case c == 'x' && i+4 <= len(s) && isHexDigit(s[i+2]) && isHexDigit(s[i+3]):
+			v, _ := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			w.WriteByte(byte(v))
+			i += 3
+		case c >= '0' && c <= '7':
+			n := 1
+			for n < 3 && i+1+n < len(s) && s[i+1+n] >= '0' && s[i+1+n] <= '7' {
+				n++
+			}
+			v, _ := strconv.ParseUint(s[i+1:i+1+n], 8, 16)
+			w.WriteByte(byte(v))
+			i += n
+		case c == 'u' || c == 'U':
+			digits := 4
+			if c == 'U' {
+				digits = 8
+			}
+			r, ok := parseHexRune(s, i+2, digits)
+			if !ok {
+				w.WriteByte('\\')
+				continue
+			}
+			i += 1 + digits
+			// a UTF-16 surrogate pair, as JSON writes characters beyond the BMP
+			if utf16.IsSurrogate(r) && i+7 <= len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+				if low, ok := parseHexRune(s, i+3, 4); ok {
+					if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			w.WriteRune(r)
+		default:
+			w.WriteByte('\\')
+		}
+	}
+}
+
+func parseHexRune(s string, start, digits int) (rune, bool) {
+	if start+digits > len(s) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s[start:start+digits], 16, 32)
+	if err != nil || v > utf8.MaxRune {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// add yours here
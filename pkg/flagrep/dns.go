@@ -0,0 +1,161 @@
+package flagrep
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dnsPayload is what the queries to one domain carry in their subdomain
+// labels.
+type dnsPayload struct {
+	domain  string
+	line    int // of the first query
+	queries int
+	// the labels joined, and decoded with encoding when one fits
+	labels   string
+	encoding string
+	decoded  string
+}
+
+// payloads joined from fewer label characters than this are ordinary
+// names such as www or mail
+const minDNSPayload = 16
+
+// the start of a payload that is logged
+const maxLoggedPayload = 80
+
+// query names in logs, captures and zone files: three or more labels
+// ending in an alphabetic top-level domain, with the root's dot or without
+var dnsNameRe = regexp.MustCompile(`(?i)\b(?:[a-z0-9_-]{1,63}\.){2,}[a-z][a-z0-9-]{0,62}[a-z0-9]\b\.?`)
+
+// second-level labels that ccTLDs register domains under, as in example.co.uk
+var dnsSecondLevel = map[string]bool{
+	"ac": true, "co": true, "com": true, "edu": true, "gov": true, "net": true, "org": true,
+}
+
+// the encodings exfiltration tools put in labels, tried in this order; hex
+// digits are Base32 and Base64 too, and Base32 is Base64, so the narrower
+// alphabets go first. DNS does not preserve case reliably and has no room
+// for padding, so Base32 is read in either case and nothing is padded.
+var dnsEncodings = []struct {
+	name   string
+	decode func(string) ([]byte, error)
+}{
+	{"hex", hex.DecodeString},
+	{"base32", func(s string) ([]byte, error) {
+		return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(s))
+	}},
+	{"base64_url", base64.RawURLEncoding.DecodeString},
+	{"base64", base64.RawStdEncoding.DecodeString},
+}
+
+// dnsQuery is a query name, without the domain it is under.
+type dnsQuery struct {
+	line   int
+	labels []string
+}
+
+// dnsPayloads finds the query names in DNS logs or zone data and joins the
+// subdomain labels of the names under each domain, in file order with
+// repeated queries dropped. A leading numeric label that all the names of a
+// domain have is taken for a sequence number: the names are put in its
+// order and it is left out, as are labels that every name of a domain has,
+// such as the x in <chunk>.x.evil.com or a tool's prefix. The joined labels
+// are then decoded with the first of dnsEncodings that fits them all.
+func dnsPayloads(text string) []dnsPayload {
+	queries := make(map[string][]dnsQuery)
+	seen := make(map[string]bool)
+	var domains []string
+	line := 0
+	for l := range strings.Lines(text) {
+		line++
+		for _, name := range dnsNameRe.FindAllString(l, -1) {
+			name = strings.TrimSuffix(name, ".")
+			if seen[strings.ToLower(name)] {
+				continue
+			}
+			seen[strings.ToLower(name)] = true
+			labels := strings.Split(name, ".")
+			n := 2
+			if tld := labels[len(labels)-1]; len(tld) == 2 && dnsSecondLevel[strings.ToLower(labels[len(labels)-2])] {
+				n = 3
+			}
+			if len(labels) <= n {
+				continue
+			}
+			domain := strings.ToLower(strings.Join(labels[len(labels)-n:], "."))
+			if _, ok := queries[domain]; !ok {
+				domains = append(domains, domain)
+			}
+			queries[domain] = append(queries[domain], dnsQuery{line: line, labels: labels[:len(labels)-n]})
+		}
+	}
+
+	var payloads []dnsPayload
+	for _, domain := range domains {
+		qs := queries[domain]
+		sequenced := len(qs) > 1
+		for _, q := range qs {
+			sequenced = sequenced && len(q.labels) > 1 && isDigits(q.labels[0])
+		}
+		if sequenced {
+			slices.SortStableFunc(qs, func(a, b dnsQuery) int {
+				x, _ := strconv.Atoi(a.labels[0])
+				y, _ := strconv.Atoi(b.labels[0])
+				return x - y
+			})
+		}
+		if sequenced {
+			for i := range qs {
+				qs[i].labels = qs[i].labels[1:]
+			}
+		}
+		shared := sharedLabels(qs)
+		var joined strings.Builder
+		for _, q := range qs {
+			for _, label := range q.labels {
+				if !shared[label] {
+					joined.WriteString(label)
+				}
+			}
+		}
+		if joined.Len() < minDNSPayload {
+			continue
+		}
+		p := dnsPayload{domain: domain, line: qs[0].line, queries: len(qs), labels: joined.String()}
+		for _, e := range dnsEncodings {
+			if data, err := e.decode(p.labels); err == nil {
+				p.encoding, p.decoded = e.name, string(data)
+				break
+			}
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+// sharedLabels returns the labels that are in every one of two or more
+// names, which carry nothing.
+func sharedLabels(qs []dnsQuery) map[string]bool {
+	shared := make(map[string]bool)
+	if len(qs) < 2 {
+		return shared
+	}
+	counts := make(map[string]int)
+	for _, q := range qs {
+		for _, label := range slices.Compact(slices.Sorted(slices.Values(q.labels))) {
+			counts[label]++
+		}
+	}
+	for label, n := range counts {
+		if n == len(qs) {
+			shared[label] = true
+		}
+	}
+	return shared
+}
@@ -0,0 +1,62 @@
+package flagrep
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDNSPayloads(t *testing.T) {
+	// dnsmasq, out of order and with a retried query
+	payload := hex.EncodeToString([]byte("flag{exfil_over_dns}"))
+	var log strings.Builder
+	for _, seq := range []int{1, 0, 2, 0, 3, 4} {
+		fmt.Fprintf(&log, "dnsmasq[412]: query[A] %d.%s.evil.example from 10.0.0.2\n", seq, payload[8*seq:8*seq+8])
+		log.WriteString("dnsmasq[412]: query[AAAA] www.example.com from 10.0.0.3\n")
+	}
+	// a zone file, Base32 in lower case split over two labels per name
+	encoded := strings.ToLower(strings.TrimRight(base32.StdEncoding.EncodeToString([]byte("flag{zone_data}")), "="))
+	for i := 0; i < len(encoded); i += 16 {
+		chunk := encoded[i:min(i+16, len(encoded))]
+		half := (len(chunk) + 1) / 2
+		fmt.Fprintf(&log, "%s.%s.c2.co.uk. 300 IN A 192.0.2.1\n", chunk[:half], chunk[half:])
+	}
+
+	got := make(map[string]dnsPayload)
+	for _, p := range dnsPayloads(log.String()) {
+		got[p.domain] = p
+	}
+	if len(got) != 2 {
+		t.Errorf("got payloads for %d domains: %+v", len(got), got)
+	}
+	if p := got["evil.example"]; p.encoding != "hex" || p.decoded != "flag{exfil_over_dns}" || p.queries != 5 || p.line != 3 {
+		t.Errorf("evil.example: got %+v", p)
+	}
+	if p := got["c2.co.uk"]; p.encoding != "base32" || p.decoded != "flag{zone_data}" {
+		t.Errorf("c2.co.uk: got %+v", p)
+	}
+
+	searcher := NewSearcher(nil, "flag{exfil", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.DNS = true
+	searcher.scan(context.Background(), []byte(log.String()), "dnsmasq.log")
+	if len(out.matches) != 1 || out.matches[0].Field != "(DNS evil.example hex)" {
+		t.Errorf("got %+v", out.matches)
+	}
+}
+
+func TestDNSPayloadsSharedLabels(t *testing.T) {
+	payload := hex.EncodeToString([]byte("flag{dns_exfil_here}"))
+	var log strings.Builder
+	for i := 0; i < len(payload); i += 10 {
+		fmt.Fprintf(&log, "query[A] dnscat.%s.x.evil.com from 10.0.0.2\n", payload[i:min(i+10, len(payload))])
+	}
+	payloads := dnsPayloads(log.String())
+	if len(payloads) != 1 || payloads[0].encoding != "hex" || payloads[0].decoded != "flag{dns_exfil_here}" {
+		t.Errorf("got %+v", payloads)
+	}
+}
@@ -0,0 +1,25 @@
+// Package flagrep searches files for a pattern hidden behind layers of
+// encoding. Every file is decoded breadth-first with combinations of
+// decoders (Base64, Base32, hex, ROT13, ROT47, reversal, ...) up to a
+// depth, and the pattern is searched in every state reached.
+//
+// Scan is the simplest entry point:
+//
+//	matches, wait, err := flagrep.Scan(ctx, flagrep.Options{
+//		Paths:     []string{"./challenge"},
+//		Pattern:   "flag{",
+//		Recursive: true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for m := range matches {
+//		fmt.Println(m.File, m.Decoders, m.Text)
+//	}
+//	if err := wait(); err != nil {
+//		return err
+//	}
+//
+// Searcher exposes the remaining knobs (baselines, ignore lists, hash lists,
+// YARA) and reports to an Output instead of a channel.
+package flagrep
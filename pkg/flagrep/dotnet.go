@@ -0,0 +1,356 @@
+package flagrep
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// DotNetInfo describes the CLR metadata of a .NET assembly.
+type DotNetInfo struct {
+	// the metadata version string, e.g. "v4.0.30319"
+	Runtime  string `json:"runtime"`
+	Assembly string `json:"assembly,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// from the TargetFrameworkAttribute, e.g. ".NETFramework,Version=v4.8"
+	Framework string `json:"target_framework,omitempty"`
+	// the string literals of the code, stored as UTF-16 in the #US heap
+	UserStrings []string `json:"user_strings,omitempty"`
+}
+
+// metadata tables up to Assembly, the last one whose offset is needed
+const (
+	mdModule = iota
+	mdTypeRef
+	mdTypeDef
+	mdFieldPtr
+	mdField
+	mdMethodPtr
+	mdMethodDef
+	mdParamPtr
+	mdParam
+	mdInterfaceImpl
+	mdMemberRef
+	mdConstant
+	mdCustomAttribute
+	mdFieldMarshal
+	mdDeclSecurity
+	mdClassLayout
+	mdFieldLayout
+	mdStandAloneSig
+	mdEventMap
+	mdEventPtr
+	mdEvent
+	mdPropertyMap
+	mdPropertyPtr
+	mdProperty
+	mdMethodSemantics
+	mdMethodImpl
+	mdModuleRef
+	mdTypeSpec
+	mdImplMap
+	mdFieldRVA
+	mdEncLog
+	mdEncMap
+	mdAssembly
+	mdAssemblyRef       = 0x23
+	mdFile              = 0x26
+	mdExportedType      = 0x27
+	mdManifestResource  = 0x28
+	mdGenericParam      = 0x2a
+	mdMethodSpec        = 0x2b
+	mdGenericParamConst = 0x2c
+)
+
+// coded indexes, the tables they point into; -1 for unused tags
+var (
+	mdTypeDefOrRef       = []int{mdTypeDef, mdTypeRef, mdTypeSpec}
+	mdHasConstant        = []int{mdField, mdParam, mdProperty}
+	mdHasFieldMarshal    = []int{mdField, mdParam}
+	mdHasDeclSecurity    = []int{mdTypeDef, mdMethodDef, mdAssembly}
+	mdMemberRefParent    = []int{mdTypeDef, mdTypeRef, mdModuleRef, mdMethodDef, mdTypeSpec}
+	mdHasSemantics       = []int{mdEvent, mdProperty}
+	mdMethodDefOrRef     = []int{mdMethodDef, mdMemberRef}
+	mdMemberForwarded    = []int{mdField, mdMethodDef}
+	mdResolutionScope    = []int{mdModule, mdModuleRef, mdAssemblyRef, mdTypeRef}
+	mdCustomAttrType     = []int{-1, -1, mdMethodDef, mdMemberRef, -1}
+	mdHasCustomAttribute = []int{
+		mdMethodDef, mdField, mdTypeRef, mdTypeDef, mdParam, mdInterfaceImpl, mdMemberRef, mdModule,
+		mdDeclSecurity, mdProperty, mdEvent, mdStandAloneSig, mdModuleRef, mdTypeSpec, mdAssembly,
+		mdAssemblyRef, mdFile, mdExportedType, mdManifestResource, mdGenericParam, mdGenericParamConst,
+		mdMethodSpec,
+	}
+)
+
+// metadata column kinds besides fixed sizes of 1 to 4 bytes
+type mdColumn any
+
+type (
+	mdString struct{}
+	mdGUID   struct{}
+	mdBlob   struct{}
+	mdIndex  int   // into one table
+	mdCoded  []int // into one of several
+)
+
+// the columns of the tables before Assembly, from ECMA-335 II.22
+var mdSchema = [mdAssembly][]mdColumn{
+	mdModule:          {2, mdString{}, mdGUID{}, mdGUID{}, mdGUID{}},
+	mdTypeRef:         {mdCoded(mdResolutionScope), mdString{}, mdString{}},
+	mdTypeDef:         {4, mdString{}, mdString{}, mdCoded(mdTypeDefOrRef), mdIndex(mdField), mdIndex(mdMethodDef)},
+	mdFieldPtr:        {mdIndex(mdField)},
+	mdField:           {2, mdString{}, mdBlob{}},
+	mdMethodPtr:       {mdIndex(mdMethodDef)},
+	mdMethodDef:       {4, 2, 2, mdString{}, mdBlob{}, mdIndex(mdParam)},
+	mdParamPtr:        {mdIndex(mdParam)},
+	mdParam:           {2, 2, mdString{}},
+	mdInterfaceImpl:   {mdIndex(mdTypeDef), mdCoded(mdTypeDefOrRef)},
+	mdMemberRef:       {mdCoded(mdMemberRefParent), mdString{}, mdBlob{}},
+	mdConstant:        {2, mdCoded(mdHasConstant), mdBlob{}},
+	mdCustomAttribute: {mdCoded(mdHasCustomAttribute), mdCoded(mdCustomAttrType), mdBlob{}},
+	mdFieldMarshal:    {mdCoded(mdHasFieldMarshal), mdBlob{}},
+	mdDeclSecurity:    {2, mdCoded(mdHasDeclSecurity), mdBlob{}},
+	mdClassLayout:     {2, 4, mdIndex(mdTypeDef)},
+	mdFieldLayout:     {4, mdIndex(mdField)},
+	mdStandAloneSig:   {mdBlob{}},
+	mdEventMap:        {mdIndex(mdTypeDef), mdIndex(mdEvent)},
+	mdEventPtr:        {mdIndex(mdEvent)},
+	mdEvent:           {2, mdString{}, mdCoded(mdTypeDefOrRef)},
+	mdPropertyMap:     {mdIndex(mdTypeDef), mdIndex(mdProperty)},
+	mdPropertyPtr:     {mdIndex(mdProperty)},
+	mdProperty:        {2, mdString{}, mdBlob{}},
+	mdMethodSemantics: {2, mdIndex(mdMethodDef), mdCoded(mdHasSemantics)},
+	mdMethodImpl:      {mdIndex(mdTypeDef), mdCoded(mdMethodDefOrRef), mdCoded(mdMethodDefOrRef)},
+	mdModuleRef:       {mdString{}},
+	mdTypeSpec:        {mdBlob{}},
+	mdImplMap:         {2, mdCoded(mdMemberForwarded), mdString{}, mdIndex(mdModuleRef)},
+	mdFieldRVA:        {4, mdIndex(mdField)},
+	mdEncLog:          {4, 4},
+	mdEncMap:          {4},
+}
+
+// the most #US strings kept, against malformed heaps
+const maxUserStrings = 1 << 16
+
+// frameworks named by TargetFrameworkAttribute
+var targetFrameworks = [][]byte{
+	[]byte(".NETFramework,Version="),
+	[]byte(".NETCoreApp,Version="),
+	[]byte(".NETStandard,Version="),
+	[]byte(".NETPortable,Version="),
+}
+
+// dotNetInfo reads the CLR header and metadata of a PE file, nil when it
+// is not a .NET assembly.
+func (img *peImage) dotNetInfo() *DotNetInfo {
+	dir := img.dir(pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR)
+	cor := img.at(dir.VirtualAddress, 16)
+	if dir.VirtualAddress == 0 || cor == nil {
+		return nil
+	}
+	md := img.at(binary.LittleEndian.Uint32(cor[8:]), int(binary.LittleEndian.Uint32(cor[12:])))
+	if len(md) < 16 || string(md[:4]) != "BSJB" {
+		return nil
+	}
+	n := int(binary.LittleEndian.Uint32(md[12:]))
+	if n > len(md)-20 {
+		return nil
+	}
+	info := &DotNetInfo{Runtime: string(bytes.TrimRight(md[16:16+n], "\x00"))}
+
+	streams := make(map[string][]byte)
+	pos := 16 + n + 2
+	count := int(binary.LittleEndian.Uint16(md[pos:]))
+	pos += 2
+	for range count {
+		if pos+8 > len(md) {
+			break
+		}
+		off, size := uint64(binary.LittleEndian.Uint32(md[pos:])), uint64(binary.LittleEndian.Uint32(md[pos+4:]))
+		end := bytes.IndexByte(md[pos+8:], 0)
+		if end < 0 {
+			break
+		}
+		name := string(md[pos+8 : pos+8+end])
+		// names are padded to four bytes
+		pos += 8 + (end+4)&^3
+		if off+size <= uint64(len(md)) {
+			streams[name] = md[off : off+size]
+		}
+	}
+
+	info.UserStrings = userStrings(streams["#US"])
+	info.Framework = targetFramework(streams["#Blob"])
+	tables := streams["#~"]
+	if tables == nil {
+		// uncompressed tables, as left by edit and continue
+		tables = streams["#-"]
+	}
+	if a, ok := readAssemblyRow(tables); ok {
+		info.Assembly = heapString(streams["#Strings"], a.name)
+		info.Version = fmt.Sprintf("%d.%d.%d.%d", a.version[0], a.version[1], a.version[2], a.version[3])
+	}
+	return info
+}
+
+// blobLength decodes the compressed length that starts every #US and #Blob
+// entry, returning it and how many bytes it took, 0 when it is invalid.
+func blobLength(b []byte) (int, int) {
+	switch {
+	case len(b) >= 1 && b[0]&0x80 == 0:
+		return int(b[0]), 1
+	case len(b) >= 2 && b[0]&0xc0 == 0x80:
+		return int(b[0]&0x3f)<<8 | int(b[1]), 2
+	case len(b) >= 4 && b[0]&0xe0 == 0xc0:
+		return int(b[0]&0x1f)<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3]), 4
+	}
+	return 0, 0
+}
+
+// userStrings decodes the #US heap: UTF-16 strings, each followed by a flag
+// byte, after an empty first entry.
+func userStrings(heap []byte) []string {
+	var out []string
+	for pos := 1; pos < len(heap) && len(out) < maxUserStrings; {
+		n, size := blobLength(heap[pos:])
+		if size == 0 || pos+size+n > len(heap) {
+			break
+		}
+		if n > 1 {
+			out = append(out, decodeUTF16LE(heap[pos+size:pos+size+n-1]))
+		}
+		pos += size + n
+	}
+	return out
+}
+
+// targetFramework finds the argument of the TargetFrameworkAttribute in the
+// #Blob heap, where custom attribute values are kept.
+func targetFramework(heap []byte) string {
+	for _, prefix := range targetFrameworks {
+		i := bytes.Index(heap, prefix)
+		// the length of the string argument comes first
+		if i < 1 || int(heap[i-1]) < len(prefix) || i+int(heap[i-1]) > len(heap) {
+			continue
+		}
+		return string(heap[i : i+int(heap[i-1])])
+	}
+	return ""
+}
+
+func heapString(heap []byte, index uint32) string {
+	if uint64(index) >= uint64(len(heap)) {
+		return ""
+	}
+	s := heap[index:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+type assemblyRow struct {
+	version [4]uint16
+	name    uint32
+}
+
+// readAssemblyRow finds the Assembly row in the #~ stream by adding up the
+// sizes of the tables before it, whose rows depend on the row counts and
+// heap sizes.
+func readAssemblyRow(tables []byte) (assemblyRow, bool) {
+	if len(tables) < 24 {
+		return assemblyRow{}, false
+	}
+	heapSizes := tables[6]
+	valid := binary.LittleEndian.Uint64(tables[8:])
+	var rows [64]uint64
+	pos := 24
+	for t := range 64 {
+		if valid&(1<<t) == 0 {
+			continue
+		}
+		if pos+4 > len(tables) {
+			return assemblyRow{}, false
+		}
+		rows[t] = uint64(binary.LittleEndian.Uint32(tables[pos:]))
+		pos += 4
+	}
+	if rows[mdAssembly] == 0 {
+		return assemblyRow{}, false
+	}
+
+	heapIndex := func(flag byte) uint64 {
+		if heapSizes&flag != 0 {
+			return 4
+		}
+		return 2
+	}
+	size := func(c mdColumn) uint64 {
+		switch c := c.(type) {
+		case int:
+			return uint64(c)
+		case mdString:
+			return heapIndex(0x01)
+		case mdGUID:
+			return heapIndex(0x02)
+		case mdBlob:
+			return heapIndex(0x04)
+		case mdIndex:
+			if rows[c] < 1<<16 {
+				return 2
+			}
+			return 4
+		case mdCoded:
+			tag := bits.Len(uint(len(c) - 1))
+			for _, t := range c {
+				if t >= 0 && rows[t] >= 1<<(16-tag) {
+					return 4
+				}
+			}
+			return 2
+		}
+		return 0
+	}
+
+	offset := uint64(pos)
+	for t, columns := range mdSchema {
+		var row uint64
+		for _, c := range columns {
+			row += size(c)
+		}
+		offset += rows[t] * row
+		if offset > uint64(len(tables)) {
+			return assemblyRow{}, false
+		}
+	}
+	// HashAlgId, the version, Flags and PublicKey come before Name
+	nameAt := offset + 16 + size(mdBlob{})
+	if nameAt+size(mdString{}) > uint64(len(tables)) {
+		return assemblyRow{}, false
+	}
+	var a assemblyRow
+	for i := range a.version {
+		a.version[i] = binary.LittleEndian.Uint16(tables[offset+4+2*uint64(i):])
+	}
+	if size(mdString{}) == 4 {
+		a.name = binary.LittleEndian.Uint32(tables[nameAt:])
+	} else {
+		a.name = uint32(binary.LittleEndian.Uint16(tables[nameAt:]))
+	}
+	return a, true
+}
+
+// dotNetUserStrings returns the #US strings of a .NET assembly, nil for
+// anything else.
+func dotNetUserStrings(content []byte) []string {
+	f := openPE(content)
+	if f == nil {
+		return nil
+	}
+	if info := newPEImage(f).dotNetInfo(); info != nil {
+		return info.UserStrings
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package flagrep
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EncoderFunc is the forward direction of the decoder of the same name.
+type EncoderFunc func(string) string
+
+func Encoders() map[string]EncoderFunc {
+	return map[string]EncoderFunc{
+		"reverse":            reverseEncoder,
+		"space_removal":      spaceInsertionEncoder,
+		"base64":             func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"base64_url":         func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) },
+		"base32":             func(s string) string { return base32.StdEncoding.EncodeToString([]byte(s)) },
+		"hex_with_spaces":    func(s string) string { return hexBytes(s, "", " ") },
+		"hex_without_spaces": func(s string) string { return hex.EncodeToString([]byte(s)) },
+		"hex_with_prefix":    func(s string) string { return hexBytes(s, "0x", " ") },
+		// both are their own inverse
+		"rot13":          func(s string) string { out, _ := rot13Decoder(s); return out },
+		"rot47":          func(s string) string { out, _ := rot47Decoder(s); return out },
+		"string_escapes": func(s string) string { return `"` + hexBytes(s, `\x`, "") + `"` },
+		"sql_literals":   func(s string) string { return "UNHEX('" + hex.EncodeToString([]byte(s)) + "')" },
+		"base64_block":   pemEncoder,
+	}
+}
+
+// "Hello" -> "olleH"
+func reverseEncoder(s string) string {
+	out, _ := reverseDecoder(s)
+	return out
+}
+
+// "Hello" -> "H e l l o"
+func spaceInsertionEncoder(s string) string {
+	return strings.Join(strings.Split(s, ""), " ")
+}
+
+// "Hi" -> "0x48 0x69"
+func hexBytes(s, prefix, sep string) string {
+	parts := make([]string, len(s))
+	for i := 0; i < len(s); i++ {
+		parts[i] = prefix + hex.EncodeToString([]byte{s[i]})
+	}
+	return strings.Join(parts, sep)
+}
+
+// "Hello" -> "-----BEGIN CERTIFICATE-----\nSGVsbG8=\n-----END CERTIFICATE-----",
+// the wrapping certutil -encode gives any file
+func pemEncoder(s string) string {
+	var b strings.Builder
+	b.WriteString("-----BEGIN CERTIFICATE-----\n")
+	for line := range slices.Chunk([]byte(base64.StdEncoding.EncodeToString([]byte(s))), 64) {
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("-----END CERTIFICATE-----")
+	return b.String()
+}
+
+// EncodeChain applies the encoders in chain in order, so that decoding with
+// the same chain reversed gives back s.
+func EncodeChain(s string, chain []string) (string, error) {
+	encoders := Encoders()
+	for _, name := range chain {
+		encode, ok := encoders[name]
+		if !ok {
+			return "", fmt.Errorf("unknown encoder %q", name)
+		}
+		s = encode(s)
+	}
+	return s, nil
+}
+
+// DecodeChain undoes EncodeChain.
+func DecodeChain(s string, chain []string) (string, error) {
+	decoders := Decoders()
+	for i := len(chain) - 1; i >= 0; i-- {
+		decode, ok := decoders[chain[i]]
+		if !ok {
+			return "", fmt.Errorf("unknown decoder %q", chain[i])
+		}
+		var err error
+		if s, err = decode(s); err != nil {
+			return "", fmt.Errorf("%s: %w", chain[i], err)
+		}
+	}
+	return s, nil
+}
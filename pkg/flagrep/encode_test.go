@@ -0,0 +1,24 @@
+package flagrep
+
+import (
+	"testing"
+)
+
+func TestEncodersRoundTrip(t *testing.T) {
+	const input = "flag{round_trip}"
+	for name := range Encoders() {
+		chain := []string{name}
+		encoded, err := EncodeChain(input, chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded, err := DecodeChain(encoded, chain); err != nil || decoded != input {
+			t.Errorf("%s: %q decodes to %q (%v)", name, encoded, decoded, err)
+		}
+	}
+	for name := range Decoders() {
+		if _, ok := Encoders()[name]; !ok {
+			t.Errorf("decoder %s has no encoder", name)
+		}
+	}
+}
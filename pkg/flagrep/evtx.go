@@ -0,0 +1,330 @@
+package flagrep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// evtxValue is a text value of a Windows event log record, such as the
+// ScriptBlockText of a PowerShell 4104 event.
+type evtxValue struct {
+	record  int    // EventRecordID
+	eventID int    // -1 when the event has none
+	name    string // Data Name or element the value fills in
+	text    string
+}
+
+const (
+	evtxChunkSize = 0x10000
+	// records start after the chunk header and its string and template tables
+	evtxRecordsOffset = 0x200
+	// nested BinXML values deeper than this are not followed
+	evtxMaxNesting = 8
+)
+
+// BinXML tokens, the low bits; 0x40 marks "more" variants with the same layout
+const (
+	evtxEOF               = 0x00
+	evtxOpenStartElement  = 0x01
+	evtxCloseStartElement = 0x02
+	evtxCloseEmptyElement = 0x03
+	evtxEndElement        = 0x04
+	evtxValueText         = 0x05
+	evtxAttribute         = 0x06
+	evtxCDATA             = 0x07
+	evtxCharRef           = 0x08
+	evtxEntityRef         = 0x09
+	evtxPITarget          = 0x0a
+	evtxPIData            = 0x0b
+	evtxTemplateInstance  = 0x0c
+	evtxSubstitution      = 0x0d
+	evtxOptSubstitution   = 0x0e
+	evtxFragmentHeader    = 0x0f
+)
+
+// substitution value types worth searching
+const (
+	evtxString      = 0x01
+	evtxANSIString  = 0x02
+	evtxUInt16      = 0x06
+	evtxBinary      = 0x0e
+	evtxBinXML      = 0x21
+	evtxStringArray = 0x81
+)
+
+// evtxValues returns the text values of every record in a Windows event
+// log (.evtx) file, ok is false for anything that is not one. Records are
+// BinXML: a template shared by the events of a chunk and the values that
+// fill in its substitutions, so the values are what varies between events
+// and what is searched. Corrupted chunks and records are skipped.
+func evtxValues(content []byte) (values []evtxValue, ok bool) {
+	if !bytes.HasPrefix(content, []byte("ElfFile\x00")) {
+		return nil, false
+	}
+	for off := 0x1000; off+evtxChunkSize <= len(content); off += evtxChunkSize {
+		chunk := content[off : off+evtxChunkSize]
+		if !bytes.HasPrefix(chunk, []byte("ElfChnk\x00")) {
+			continue
+		}
+		c := &evtxChunk{data: chunk, templates: make(map[uint32]*evtxTemplate)}
+		values = c.records(values)
+	}
+	return values, true
+}
+
+type evtxChunk struct {
+	data      []byte
+	templates map[uint32]*evtxTemplate
+}
+
+// evtxTemplate is what searching needs to know about a template.
+type evtxTemplate struct {
+	names map[int]string // substitution index to the name of what it fills in
+	// EventID, from a substitution or written in the template itself
+	eventIDSub int
+	eventID    int
+}
+
+func (c *evtxChunk) records(values []evtxValue) []evtxValue {
+	end := min(int(binary.LittleEndian.Uint32(c.data[0x30:])), len(c.data))
+	if end <= evtxRecordsOffset {
+		// not closed properly, as in the log a live system is writing to
+		end = len(c.data)
+	}
+	for pos := evtxRecordsOffset; pos+24 <= end; {
+		size := int(binary.LittleEndian.Uint32(c.data[pos+4:]))
+		if !bytes.HasPrefix(c.data[pos:], []byte("\x2a\x2a\x00\x00")) || size < 28 || pos+size > end {
+			break
+		}
+		record := int(binary.LittleEndian.Uint64(c.data[pos+8:]))
+		values = c.fragment(values, &evtxReader{b: c.data[:pos+size-4], pos: pos + 24}, record, 0)
+		pos += size
+	}
+	return values
+}
+
+// fragment reads a BinXML fragment holding a template instance and appends
+// its text values.
+func (c *evtxChunk) fragment(values []evtxValue, r *evtxReader, record, nesting int) []evtxValue {
+	if r.u8() != evtxFragmentHeader {
+		return values
+	}
+	r.skip(3)
+	if r.u8() != evtxTemplateInstance {
+		return values
+	}
+	r.skip(5)
+	defOff := r.u32()
+	if int(defOff) == r.pos {
+		// defined here, the first time the chunk uses it
+		r.skip(20)
+		r.skip(int(r.u32()))
+	}
+	t := c.template(defOff)
+
+	count := int(r.u32())
+	if r.bad || count > r.remaining()/4 {
+		return values
+	}
+	sizes := make([]int, count)
+	types := make([]byte, count)
+	for i := range count {
+		sizes[i] = int(r.u16())
+		types[i] = r.u8()
+		r.skip(1)
+	}
+
+	eventID := t.eventID
+	start := len(values)
+	for i := range count {
+		data := r.bytes(sizes[i])
+		if r.bad {
+			break
+		}
+		var text string
+		switch types[i] {
+		case evtxString:
+			text = strings.TrimRight(decodeUTF16LE(data), "\x00")
+		case evtxANSIString:
+			text = strings.TrimRight(string(data), "\x00")
+		case evtxStringArray:
+			text = strings.Join(strings.FieldsFunc(decodeUTF16LE(data), func(r rune) bool { return r == 0 }), "\n")
+		case evtxBinary:
+			text = string(data)
+		case evtxUInt16:
+			if i == t.eventIDSub && len(data) == 2 {
+				eventID = int(binary.LittleEndian.Uint16(data))
+			}
+		case evtxBinXML:
+			// UserData and other nested documents; their offsets are
+			// relative to the chunk as well
+			if nesting < evtxMaxNesting {
+				nested := &evtxReader{b: c.data[:r.pos], pos: r.pos - len(data)}
+				values = c.fragment(values, nested, record, nesting+1)
+			}
+		}
+		if text != "" {
+			values = append(values, evtxValue{record: record, name: t.names[i], text: text})
+		}
+	}
+	if nesting == 0 {
+		for i := start; i < len(values); i++ {
+			values[i].eventID = eventID
+		}
+	}
+	return values
+}
+
+// template reads the template defined at off in the chunk, once.
+func (c *evtxChunk) template(off uint32) *evtxTemplate {
+	if t, ok := c.templates[off]; ok {
+		return t
+	}
+	t := &evtxTemplate{names: make(map[int]string), eventIDSub: -1, eventID: -1}
+	c.templates[off] = t
+	if int64(off)+24 > int64(len(c.data)) {
+		return t
+	}
+	size := int(binary.LittleEndian.Uint32(c.data[off+20:]))
+	start := int(off) + 24
+	r := &evtxReader{b: c.data[:min(start+size, len(c.data))], pos: start}
+
+	// the element names from the root down, and the attribute being read
+	var elements []string
+	attribute, dataName := "", ""
+	name := func() string {
+		nameOff := r.u32()
+		return c.name(nameOff, r)
+	}
+	for !r.bad && r.remaining() > 0 {
+		token := r.u8()
+		switch token &^ 0x40 {
+		case evtxEOF:
+			return t
+		case evtxFragmentHeader:
+			r.skip(3)
+		case evtxOpenStartElement:
+			r.skip(6)
+			elements = append(elements, name())
+			if token&0x40 != 0 {
+				r.skip(4)
+			}
+			attribute, dataName = "", ""
+		case evtxCloseStartElement:
+			attribute = ""
+		case evtxCloseEmptyElement, evtxEndElement:
+			if len(elements) > 0 {
+				elements = elements[:len(elements)-1]
+			}
+			attribute = ""
+		case evtxAttribute:
+			attribute = name()
+		case evtxValueText:
+			r.skip(1)
+			text := decodeUTF16LE(r.bytes(2 * int(r.u16())))
+			switch {
+			case attribute == "Name" && len(elements) > 0 && elements[len(elements)-1] == "Data":
+				dataName = text
+			case attribute == "" && len(elements) > 0 && elements[len(elements)-1] == "EventID":
+				if id, err := strconv.Atoi(text); err == nil {
+					t.eventID = id
+				}
+			}
+		case evtxSubstitution, evtxOptSubstitution:
+			index := int(r.u16())
+			r.skip(1)
+			if len(elements) == 0 {
+				continue
+			}
+			element := elements[len(elements)-1]
+			switch {
+			case attribute != "":
+				t.names[index] = element + "." + attribute
+			case dataName != "":
+				t.names[index] = dataName
+			default:
+				t.names[index] = element
+			}
+			if element == "EventID" && attribute == "" {
+				t.eventIDSub = index
+			}
+		case evtxCDATA, evtxPIData:
+			r.skip(2 * int(r.u16()))
+		case evtxCharRef:
+			r.skip(2)
+		case evtxEntityRef, evtxPITarget:
+			name()
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// name reads the name at off in the chunk, skipping it in r when it is
+// stored inline, right where r is.
+func (c *evtxChunk) name(off uint32, r *evtxReader) string {
+	if int64(off)+8 > int64(len(c.data)) {
+		r.bad = true
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint16(c.data[off+6:]))
+	end := int(off) + 8 + 2*n
+	if end > len(c.data) {
+		r.bad = true
+		return ""
+	}
+	if int(off) == r.pos {
+		r.skip(8 + 2*n + 2)
+	}
+	return decodeUTF16LE(c.data[int(off)+8 : end])
+}
+
+// evtxReader reads little-endian fields, returning zeros and setting bad
+// once it runs past the end.
+type evtxReader struct {
+	b   []byte
+	pos int
+	bad bool
+}
+
+func (r *evtxReader) remaining() int {
+	return len(r.b) - r.pos
+}
+
+func (r *evtxReader) bytes(n int) []byte {
+	if n < 0 || n > r.remaining() {
+		r.bad = true
+		r.pos = len(r.b)
+		return nil
+	}
+	r.pos += n
+	return r.b[r.pos-n : r.pos]
+}
+
+func (r *evtxReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *evtxReader) u8() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *evtxReader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *evtxReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package flagrep
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// evtxBuilder writes BinXML into a chunk, where offsets are chunk-relative.
+type evtxBuilder struct {
+	chunk []byte
+}
+
+func (b *evtxBuilder) u8(v byte)    { b.chunk = append(b.chunk, v) }
+func (b *evtxBuilder) u16(v uint16) { b.chunk = binary.LittleEndian.AppendUint16(b.chunk, v) }
+func (b *evtxBuilder) u32(v uint32) { b.chunk = binary.LittleEndian.AppendUint32(b.chunk, v) }
+
+// name writes an inline name after its offset field.
+func (b *evtxBuilder) name(s string) {
+	b.u32(uint32(len(b.chunk) + 4))
+	b.u32(0)
+	b.u16(0)
+	b.u16(uint16(len(s)))
+	b.chunk = append(b.chunk, utf16le(s)...)
+}
+
+func (b *evtxBuilder) open(name string, attrs bool) {
+	token := byte(evtxOpenStartElement)
+	if attrs {
+		token |= 0x40
+	}
+	b.u8(token)
+	b.u16(0xffff)
+	b.u32(0)
+	b.name(name)
+	if attrs {
+		b.u32(0)
+	}
+}
+
+func (b *evtxBuilder) sub(index uint16, typ byte) {
+	b.u8(evtxSubstitution)
+	b.u16(index)
+	b.u8(typ)
+}
+
+// record writes a 4104 event with its template the first time.
+func (b *evtxBuilder) record(id uint64, script string, defOff *uint32) {
+	start := len(b.chunk)
+	b.chunk = append(b.chunk, "\x2a\x2a\x00\x00"...)
+	b.u32(0)
+	b.chunk = binary.LittleEndian.AppendUint64(b.chunk, id)
+	b.chunk = binary.LittleEndian.AppendUint64(b.chunk, 0)
+	b.chunk = append(b.chunk, evtxFragmentHeader, 1, 1, 0, evtxTemplateInstance, 1, 0, 0, 0, 0)
+	if *defOff == 0 {
+		*defOff = uint32(len(b.chunk) + 4)
+		b.u32(*defOff)
+		b.u32(0)
+		b.chunk = append(b.chunk, make([]byte, 16)...)
+		sizeAt := len(b.chunk)
+		b.u32(0)
+		body := len(b.chunk)
+		b.chunk = append(b.chunk, evtxFragmentHeader, 1, 1, 0)
+		b.open("Event", false)
+		b.u8(evtxCloseStartElement)
+		b.open("System", false)
+		b.u8(evtxCloseStartElement)
+		b.open("EventID", false)
+		b.u8(evtxCloseStartElement)
+		b.sub(0, evtxUInt16)
+		b.u8(evtxEndElement)
+		b.u8(evtxEndElement)
+		b.open("EventData", false)
+		b.u8(evtxCloseStartElement)
+		b.open("Data", true)
+		b.u8(evtxAttribute)
+		b.name("Name")
+		b.u8(evtxValueText)
+		b.u8(evtxString)
+		b.u16(uint16(len("ScriptBlockText")))
+		b.chunk = append(b.chunk, utf16le("ScriptBlockText")[:2*len("ScriptBlockText")]...)
+		b.u8(evtxCloseStartElement)
+		b.sub(1, evtxString)
+		b.u8(evtxEndElement)
+		b.u8(evtxEndElement)
+		b.u8(evtxEndElement)
+		b.u8(evtxEOF)
+		binary.LittleEndian.PutUint32(b.chunk[sizeAt:], uint32(len(b.chunk)-body))
+	} else {
+		b.u32(*defOff)
+	}
+	text := utf16le(script)
+	b.u32(2)
+	b.u16(2)
+	b.chunk = append(b.chunk, evtxUInt16, 0)
+	b.u16(uint16(len(text)))
+	b.chunk = append(b.chunk, evtxString, 0)
+	b.u16(4104)
+	b.chunk = append(b.chunk, text...)
+	b.u32(uint32(len(b.chunk) - start + 4))
+	binary.LittleEndian.PutUint32(b.chunk[start+4:], uint32(len(b.chunk)-start))
+}
+
+func TestEvtxValues(t *testing.T) {
+	b := &evtxBuilder{chunk: make([]byte, evtxRecordsOffset)}
+	copy(b.chunk, "ElfChnk\x00")
+	var defOff uint32
+	b.record(41, "Write-Host hello", &defOff)
+	payload := base64.StdEncoding.EncodeToString([]byte("iex flag{script_block}"))
+	b.record(42, payload, &defOff)
+	binary.LittleEndian.PutUint32(b.chunk[0x30:], uint32(len(b.chunk)))
+	chunk := append(b.chunk, make([]byte, evtxChunkSize-len(b.chunk))...)
+	header := make([]byte, 0x1000)
+	copy(header, "ElfFile\x00")
+	file := append(header, chunk...)
+
+	values, ok := evtxValues(file)
+	if !ok {
+		t.Fatal("not recognized as an event log")
+	}
+	if len(values) != 2 {
+		t.Fatalf("got %d values, want 2: %+v", len(values), values)
+	}
+	want := evtxValue{record: 42, eventID: 4104, name: "ScriptBlockText", text: payload}
+	if values[1] != want {
+		t.Errorf("got %+v, want %+v", values[1], want)
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), file, "PowerShell.evtx")
+	if len(out.matches) == 0 {
+		t.Fatal("no match in the script block")
+	}
+	if m := out.matches[0]; m.Record != 42 || m.Field != "EventID 4104 ScriptBlockText" || m.Line != 0 {
+		t.Errorf("match at record %d, field %q, line %d", m.Record, m.Field, m.Line)
+	}
+
+	// truncated and corrupted logs must not crash
+	for n := 0x1000; n < 0x1000+len(b.chunk); n += 7 {
+		evtxValues(file[:n])
+		corrupt := bytes.Clone(file)
+		corrupt[n] ^= 0xff
+		evtxValues(corrupt)
+	}
+}
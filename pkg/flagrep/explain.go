@@ -0,0 +1,132 @@
+package flagrep
+
+import (
+	"slices"
+	"strings"
+)
+
+// Step is one stage of a decoder chain: the content around the match before
+// any decoder ran (Decoder == "") or right after Decoder ran.
+type Step struct {
+	Decoder string `json:"decoder,omitempty"`
+	Snippet string `json:"snippet"`
+}
+
+// context shown around the match at every step
+const explainContext = 12
+
+// explainSteps shows where the match at start:end of state's content came
+// from in every earlier state of its chain, see traceMatch.
+func explainSteps(state *searchState, start, end int) []Step {
+	var steps []Step
+	traceMatch(state, start, end, func(st *searchState, start, end int) {
+		step := Step{Snippet: snippet(st.content, start, end)}
+		if st.parent != nil {
+			step.Decoder = st.appliedDecoders[len(st.appliedDecoders)-1]
+		}
+		steps = append(steps, step)
+	})
+	// collected from the match back to the input
+	slices.Reverse(steps)
+	return steps
+}
+
+// originalOffset is where the match at start:end of state's content
+// starts in the content the search began with.
+func originalOffset(state *searchState, start, end int) int {
+	offset := start
+	traceMatch(state, start, end, func(st *searchState, start, end int) {
+		offset = start
+	})
+	return offset
+}
+
+// traceMatch follows the match at start:end of state's content back through
+// its chain, calling visit with its position in every state from state to
+// the root. The match is traced by encoding it with each decoder's inverse,
+// or as it is for decoders that leave it alone like space_removal; where
+// neither is found, e.g. base64 out of alignment, the position is estimated
+// from the lengths.
+func traceMatch(state *searchState, start, end int, visit func(st *searchState, start, end int)) {
+	encoders := Encoders()
+	text := state.content[start:end]
+	for st := state; st != nil; st = st.parent {
+		visit(st, start, end)
+		if st.parent == nil {
+			break
+		}
+		decoder := st.appliedDecoders[len(st.appliedDecoders)-1]
+
+		prev := st.parent.content
+		ratio := float64(len(prev)) / float64(len(st.content))
+		estStart, estEnd := int(float64(start)*ratio), min(int(float64(end)*ratio+0.5), len(prev))
+		if decoder == "reverse" {
+			estStart, estEnd = len(prev)-estEnd, len(prev)-estStart
+		}
+		if text != "" {
+			candidates := []string{text}
+			if encode, ok := encoders[decoder]; ok {
+				if enc := encode(text); enc != "" {
+					candidates = []string{enc, text}
+				}
+			}
+			found := false
+			for _, c := range candidates {
+				if i := nearestIndex(prev, c, estStart); i >= 0 {
+					start, end, text = i, i+len(c), c
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+		}
+		start, end = estStart, estEnd
+		// no exact text to follow further back
+		text = ""
+	}
+}
+
+// nearestIndex returns the index of the occurrence of sub in s closest to
+// near, or -1.
+func nearestIndex(s, sub string, near int) int {
+	best := -1
+	for i := 0; i <= len(s)-len(sub); {
+		j := strings.Index(s[i:], sub)
+		if j < 0 {
+			break
+		}
+		j += i
+		if best < 0 || abs(j-near) < abs(best-near) {
+			best = j
+		}
+		if j > near {
+			break
+		}
+		i = j + 1
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// snippet returns start:end of content with some context, shortened to a
+// readable length and with unprintable bytes shown as dots.
+func snippet(content string, start, end int) string {
+	const maxLen = 80
+	end = min(end, start+maxLen)
+	s := content[max(start-explainContext, 0):min(end+explainContext, len(content))]
+	b := []byte(s)
+	for i, c := range b {
+		if c < 0x20 || c > 0x7e {
+			b[i] = '.'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,161 @@
+package flagrep
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ExtractedString is a run of printable characters found in binary data.
+type ExtractedString struct {
+	Offset   int
+	Encoding string
+	Text     string
+}
+
+func isPrintableASCII(b byte) bool {
+	return (b >= 0x20 && b <= 0x7e) || b == '\t'
+}
+
+// ExtractStrings returns the runs of at least minLen printable ASCII bytes,
+// like strings(1).
+func ExtractStrings(data []byte, minLen int) []ExtractedString {
+	var result []ExtractedString
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && isPrintableASCII(data[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minLen {
+			result = append(result, ExtractedString{Offset: start, Encoding: "ascii", Text: string(data[start:i])})
+		}
+		start = -1
+	}
+	return result
+}
+
+// ExtractUnicodeStrings returns the runs of at least minLen printable ASCII
+// characters encoded as UTF-16LE, the wide strings of Windows binaries.
+func ExtractUnicodeStrings(data []byte, minLen int) []ExtractedString {
+	return extractWide(data, minLen, 2, false, "utf16le")
+}
+
+// ExtractUTF16BEStrings is ExtractUnicodeStrings for big-endian UTF-16, as
+// found in Java class files and big-endian firmware.
+func ExtractUTF16BEStrings(data []byte, minLen int) []ExtractedString {
+	return extractWide(data, minLen, 2, true, "utf16be")
+}
+
+// ExtractUTF32LEStrings returns strings stored as little-endian UTF-32, the
+// wchar_t of most Unix compilers.
+func ExtractUTF32LEStrings(data []byte, minLen int) []ExtractedString {
+	return extractWide(data, minLen, 4, false, "utf32le")
+}
+
+// ExtractUTF32BEStrings returns strings stored as big-endian UTF-32.
+func ExtractUTF32BEStrings(data []byte, minLen int) []ExtractedString {
+	return extractWide(data, minLen, 4, true, "utf32be")
+}
+
+// extractWide finds printable ASCII characters stored as code units of width
+// bytes, that is the character byte followed (little-endian) or preceded
+// (big-endian) by zero bytes.
+func extractWide(data []byte, minLen, width int, bigEndian bool, encoding string) []ExtractedString {
+	charAt := 0
+	if bigEndian {
+		charAt = width - 1
+	}
+	isChar := func(i int) bool {
+		if i+width > len(data) {
+			return false
+		}
+		for j := 0; j < width; j++ {
+			if j == charAt {
+				if !isPrintableASCII(data[i+j]) {
+					return false
+				}
+			} else if data[i+j] != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	var result []ExtractedString
+	// wide strings may start at any offset, so look at every alignment
+	for align := 0; align < width; align++ {
+		var b strings.Builder
+		start := -1
+		for i := align; i < len(data)+width; i += width {
+			if isChar(i) {
+				if start < 0 {
+					start = i
+				}
+				b.WriteByte(data[i+charAt])
+				continue
+			}
+			if start >= 0 && b.Len() >= minLen {
+				result = append(result, ExtractedString{Offset: start, Encoding: encoding, Text: b.String()})
+			}
+			b.Reset()
+			start = -1
+		}
+	}
+	slices.SortFunc(result, func(a, b ExtractedString) int { return a.Offset - b.Offset })
+	return result
+}
+
+// StringExtractors maps -encoding names to extractors.
+var StringExtractors = map[string]func([]byte, int) []ExtractedString{
+	"ascii":   ExtractStrings,
+	"utf16le": ExtractUnicodeStrings,
+	"utf16be": ExtractUTF16BEStrings,
+	"utf32le": ExtractUTF32LEStrings,
+	"utf32be": ExtractUTF32BEStrings,
+}
+
+// DecodedVariant is a string after a chain of decoders.
+type DecodedVariant struct {
+	Decoders []string
+	Text     string
+}
+
+// DecodeVariants applies the decoders breadth-first up to depth and returns
+// every distinct result that is still mostly printable text.
+func DecodeVariants(text string, decoders map[string]DecoderFunc, depth int) []DecodedVariant {
+	names := slices.Sorted(maps.Keys(decoders))
+	seen := map[string]bool{text: true}
+	level := []DecodedVariant{{Text: text}}
+	var result []DecodedVariant
+
+	for d := 0; d < depth; d++ {
+		var next []DecodedVariant
+		for _, v := range level {
+			for _, name := range names {
+				decoded, err := decoders[name](v.Text)
+				if err != nil || decoded == "" || seen[decoded] || !mostlyPrintable(decoded) {
+					continue
+				}
+				seen[decoded] = true
+				chain := append(slices.Clone(v.Decoders), name)
+				next = append(next, DecodedVariant{Decoders: chain, Text: decoded})
+			}
+		}
+		result = append(result, next...)
+		level = next
+	}
+	return result
+}
+
+func mostlyPrintable(s string) bool {
+	printable := 0
+	for i := 0; i < len(s); i++ {
+		if isPrintableASCII(s[i]) || s[i] == '\n' || s[i] == '\r' {
+			printable++
+		}
+	}
+	return float64(printable)/float64(len(s)) > 0.8
+}
@@ -0,0 +1,49 @@
+package flagrep
+
+import "testing"
+
+func TestExtractStrings(t *testing.T) {
+	data := []byte("ab\x00hello\x00\x01w\x00i\x00d\x00e\x00\x00\x00xyz")
+
+	ascii := ExtractStrings(data, 4)
+	if len(ascii) != 1 || ascii[0].Text != "hello" || ascii[0].Offset != 3 {
+		t.Errorf("unexpected ASCII strings: %+v", ascii)
+	}
+
+	wide := ExtractUnicodeStrings(data, 4)
+	if len(wide) != 1 || wide[0].Text != "wide" || wide[0].Offset != 10 {
+		t.Errorf("unexpected UTF-16LE strings: %+v", wide)
+	}
+}
+
+func TestDecodeVariants(t *testing.T) {
+	variants := DecodeVariants("SGVsbG8gd29ybGQ=", Decoders(), 1)
+
+	found := false
+	for _, v := range variants {
+		if v.Text == "Hello world" && v.Decoders[0] == "base64" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected base64 variant, got %+v", variants)
+	}
+}
+
+func TestExtractWideVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		extract func([]byte, int) []ExtractedString
+		data    []byte
+	}{
+		{"utf16be", ExtractUTF16BEStrings, []byte("\xff\x00f\x00l\x00a\x00g\xff")},
+		{"utf32le", ExtractUTF32LEStrings, []byte("\xfff\x00\x00\x00l\x00\x00\x00a\x00\x00\x00g\x00\x00\x00")},
+		{"utf32be", ExtractUTF32BEStrings, []byte("\xff\x00\x00\x00f\x00\x00\x00l\x00\x00\x00a\x00\x00\x00g\xff")},
+	}
+	for _, tt := range tests {
+		found := tt.extract(tt.data, 4)
+		if len(found) != 1 || found[0].Text != "flag" || found[0].Offset != 1 || found[0].Encoding != tt.name {
+			t.Errorf("%s: unexpected strings %+v", tt.name, found)
+		}
+	}
+}
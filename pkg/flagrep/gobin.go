@@ -0,0 +1,21 @@
+package flagrep
+
+import (
+	"bytes"
+	"debug/buildinfo"
+)
+
+// goBuildInfo returns the build information embedded in a Go executable
+// (ELF, PE, Mach-O or XCOFF): Go version, main module, dependencies with
+// their versions and build settings such as -ldflags and VCS revision. ok is
+// false for anything that is not a Go binary.
+//
+// Names of go:embed files need no special handling, they are stored as plain
+// strings in the binary and are found by the regular scan.
+func goBuildInfo(content []byte) (info *buildinfo.BuildInfo, ok bool) {
+	info, err := buildinfo.Read(bytes.NewReader(content))
+	if err != nil {
+		return nil, false
+	}
+	return info, true
+}
@@ -0,0 +1,186 @@
+package flagrep
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+// harPart is a part of an HTTP exchange in a HAR file searched on its own.
+type harPart struct {
+	entry int    // 1-based
+	field string // e.g. "GET https://example.com/ response 200 body"
+	text  string
+}
+
+// bodies larger than this when decompressed are cut short, a gzip bomb
+// would otherwise take all memory
+const maxHARBody = 256 << 20
+
+// URLs longer than this are shortened in fields; data: URLs run to megabytes
+const maxHARURL = 200
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// the parts of the HTTP Archive format that are searched
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string      `json:"method"`
+				URL      string      `json:"url"`
+				Headers  []harHeader `json:"headers"`
+				PostData *struct {
+					Text string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status  int         `json:"status"`
+				Headers []harHeader `json:"headers"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// harParts returns the URL and headers, and the bodies, of the requests
+// and responses in an HTTP Archive, as browsers and proxies export it; ok
+// is false for anything that is not one. Bodies are Base64-decoded when
+// the archive stores them so, then un-chunked and decompressed as their
+// headers say, since a secret in a compressed response is not in the JSON
+// at all and the JSON around a readable one is noise. Every other string
+// in the archive, like cookies, query parameters, WebSocket messages and
+// comments, is a part of its own named by its JSON path.
+func harParts(content []byte) (parts []harPart, ok bool) {
+	trimmed := bytes.TrimLeft(content, " \t\r\n\ufeff")
+	if !bytes.HasPrefix(trimmed, []byte("{")) || !bytes.Contains(content[:min(len(content), 4096)], []byte(`"log"`)) {
+		return nil, false
+	}
+	var har harFile
+	if err := json.Unmarshal(trimmed, &har); err != nil || har.Log.Entries == nil {
+		return nil, false
+	}
+	// the same again without a model, for the rest
+	var whole map[string]any
+	json.Unmarshal(trimmed, &whole)
+	log, _ := whole["log"].(map[string]any)
+	entries, _ := log["entries"].([]any)
+	for _, leaf := range stringLeaves("", whole) {
+		if !strings.HasPrefix(leaf.path, ".log.entries[") {
+			parts = append(parts, harPart{field: leaf.path[1:], text: leaf.text})
+		}
+	}
+
+	for i, e := range har.Log.Entries {
+		url := e.Request.URL
+		if len(url) > maxHARURL {
+			url = url[:maxHARURL] + "..."
+		}
+		request := e.Request.Method + " " + url + " request"
+		response := e.Request.Method + " " + url + " response " + strconv.Itoa(e.Response.Status)
+		add := func(field, text string) {
+			if text != "" {
+				parts = append(parts, harPart{entry: i + 1, field: field, text: text})
+			}
+		}
+
+		add(request+" headers", e.Request.URL+"\n"+harHeaderLines(e.Request.Headers))
+		if e.Request.PostData != nil {
+			add(request+" body", harBody(e.Request.PostData.Text, e.Request.Headers))
+		}
+		add(response+" headers", harHeaderLines(e.Response.Headers))
+		body := e.Response.Content.Text
+		if e.Response.Content.Encoding == "base64" {
+			if data, err := base64.StdEncoding.DecodeString(body); err == nil {
+				body = string(data)
+			}
+		}
+		add(response+" body", harBody(body, e.Response.Headers))
+
+		if i < len(entries) {
+			for _, leaf := range stringLeaves("", entries[i]) {
+				if !harModeled(leaf.path[1:]) {
+					add(e.Request.Method+" "+url+" "+leaf.path[1:], leaf.text)
+				}
+			}
+		}
+	}
+	return parts, true
+}
+
+// harModeled tells whether the string at path in an entry is searched as
+// part of the headers or a body already.
+func harModeled(path string) bool {
+	switch path {
+	case "request.method", "request.url", "request.postData.text", "response.content.text", "response.content.encoding":
+		return true
+	}
+	return strings.HasPrefix(path, "request.headers[") || strings.HasPrefix(path, "response.headers[")
+}
+
+func harHeaderLines(headers []harHeader) string {
+	var b strings.Builder
+	for _, h := range headers {
+		b.WriteString(h.Name + ": " + h.Value + "\n")
+	}
+	return b.String()
+}
+
+func harHeaderValue(headers []harHeader, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return strings.ToLower(strings.TrimSpace(h.Value))
+		}
+	}
+	return ""
+}
+
+// harBody undoes the transfer and content encodings of a body that its
+// headers name, where the archive kept them; it is returned as it is when
+// that fails. Gzip is also recognized by its magic, since tools drop the
+// headers but keep the bytes.
+func harBody(body string, headers []harHeader) string {
+	if strings.Contains(harHeaderValue(headers, "Transfer-Encoding"), "chunked") {
+		if data, err := io.ReadAll(httputil.NewChunkedReader(bufio.NewReader(strings.NewReader(body)))); err == nil {
+			body = string(data)
+		}
+	}
+
+	var r io.Reader
+	var err error
+	encoding := harHeaderValue(headers, "Content-Encoding")
+	switch {
+	case encoding == "gzip" || encoding == "x-gzip" || strings.HasPrefix(body, "\x1f\x8b"):
+		r, err = gzip.NewReader(strings.NewReader(body))
+	case encoding == "deflate":
+		// zlib as the RFC says, or the raw deflate some servers send
+		r, err = zlib.NewReader(strings.NewReader(body))
+		if err != nil {
+			r, err = flate.NewReader(strings.NewReader(body)), nil
+		}
+	default:
+		return body
+	}
+	if err != nil {
+		return body
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxHARBody))
+	if err != nil && len(data) == 0 {
+		return body
+	}
+	return string(data)
+}
@@ -0,0 +1,94 @@
+package flagrep
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestHARParts(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(`{"secret":"flag{gzipped_response}"}`))
+	w.Close()
+
+	type header = map[string]string
+	har := map[string]any{"log": map[string]any{"version": "1.2", "entries": []any{
+		map[string]any{
+			"request": map[string]any{
+				"method":  "POST",
+				"url":     "https://api.example.com/login",
+				"headers": []header{{"name": "Transfer-Encoding", "value": "chunked"}},
+				"postData": map[string]any{
+					"mimeType": "application/json",
+					"text":     "b\r\n{\"pw\":\"flag\r\n6\r\n{post}\r\n2\r\n\"}\r\n0\r\n\r\n",
+				},
+			},
+			"response": map[string]any{
+				"status":  200,
+				"headers": []header{{"name": "Content-Encoding", "value": "gzip"}, {"name": "Set-Cookie", "value": "s=flag{cookie}"}},
+				"content": map[string]any{"encoding": "base64", "text": base64.StdEncoding.EncodeToString(gz.Bytes())},
+			},
+		},
+		map[string]any{
+			"request":  map[string]any{"method": "GET", "url": "https://example.com/?q=flag{url}"},
+			"response": map[string]any{"status": 404, "content": map[string]any{"text": "not found"}},
+		},
+		map[string]any{
+			"request":            map[string]any{"method": "GET", "url": "wss://example.com/ws", "cookies": []header{{"name": "s", "value": "flag{cookie_jar}"}}},
+			"response":           map[string]any{"status": 101},
+			"_webSocketMessages": []any{map[string]any{"type": "receive", "data": "flag{ws_secret}"}},
+		},
+	}, "comment": "flag{comment}"}}
+	content, _ := json.Marshal(har)
+
+	parts, ok := harParts(content)
+	if !ok {
+		t.Fatal("not recognized as a HAR file")
+	}
+	got := make(map[string]string)
+	for _, p := range parts {
+		got[p.field] = p.text
+	}
+	if body := got["POST https://api.example.com/login request body"]; body != `{"pw":"flag{post}"}` {
+		t.Errorf("request body = %q", body)
+	}
+	if body := got["POST https://api.example.com/login response 200 body"]; body != `{"secret":"flag{gzipped_response}"}` {
+		t.Errorf("response body = %q", body)
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), content, "session.har")
+	var fields []string
+	for _, m := range out.matches {
+		if len(m.Decoders) == 0 {
+			fields = append(fields, m.Field)
+			if m.Record == 0 && m.Field != "log.comment" {
+				t.Errorf("match in %s has no entry number", m.Field)
+			}
+		}
+	}
+	slices.Sort(fields)
+	want := []string{
+		"GET https://example.com/?q=flag{url} request headers",
+		"GET wss://example.com/ws _webSocketMessages[0].data",
+		"GET wss://example.com/ws request.cookies[0].value",
+		"POST https://api.example.com/login request body",
+		"POST https://api.example.com/login response 200 body",
+		"POST https://api.example.com/login response 200 headers",
+		"log.comment",
+	}
+	if !slices.Equal(fields, want) {
+		t.Errorf("matches in %q, want %q", fields, want)
+	}
+
+	if _, ok := harParts([]byte(`{"log": "not a har"}`)); ok {
+		t.Error("plain JSON recognized as a HAR file")
+	}
+}
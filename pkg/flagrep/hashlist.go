@@ -0,0 +1,85 @@
+package flagrep
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// HashList is a set of known-good file hashes. Files whose MD5, SHA-1 or
+// SHA-256 is listed are skipped without being searched.
+type HashList struct {
+	md5    map[string]struct{}
+	sha1   map[string]struct{}
+	sha256 map[string]struct{}
+}
+
+// LoadHashList reads hashes from a file with one hash per line or in NSRL
+// RDS CSV format ("SHA-1","MD5","CRC32","FileName",...). Every MD5, SHA-1 or
+// SHA-256 sized hex field on a line is taken.
+func LoadHashList(path string) (*HashList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &HashList{
+		md5:    make(map[string]struct{}),
+		sha1:   make(map[string]struct{}),
+		sha256: make(map[string]struct{}),
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Split(scanner.Text(), ",") {
+			field = strings.ToLower(strings.Trim(strings.TrimSpace(field), `"`))
+			if _, err := hex.DecodeString(field); err != nil {
+				continue
+			}
+			switch len(field) {
+			case 32:
+				list.md5[field] = struct{}{}
+			case 40:
+				list.sha1[field] = struct{}{}
+			case 64:
+				list.sha256[field] = struct{}{}
+			}
+		}
+	}
+	return list, scanner.Err()
+}
+
+func (l *HashList) Len() int {
+	return len(l.md5) + len(l.sha1) + len(l.sha256)
+}
+
+// Known reports whether content hashes to a listed value. Only the hash
+// types present in the list are computed.
+func (l *HashList) Known(content []byte) bool {
+	if l == nil {
+		return false
+	}
+	if len(l.sha256) > 0 {
+		sum := sha256.Sum256(content)
+		if _, ok := l.sha256[hex.EncodeToString(sum[:])]; ok {
+			return true
+		}
+	}
+	if len(l.sha1) > 0 {
+		sum := sha1.Sum(content)
+		if _, ok := l.sha1[hex.EncodeToString(sum[:])]; ok {
+			return true
+		}
+	}
+	if len(l.md5) > 0 {
+		sum := md5.Sum(content)
+		if _, ok := l.md5[hex.EncodeToString(sum[:])]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,698 @@
+package flagrep
+
+import (
+	"bytes"
+	"crypto/md5"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FileHeader summarizes the executable header of a file.
+type FileHeader struct {
+	Path     string        `json:"path"`
+	Format   string        `json:"format"`
+	Arch     string        `json:"arch"`
+	Bits     int           `json:"bits"`
+	Type     string        `json:"type"`
+	Entry    uint64        `json:"entry"`
+	Sections []SectionInfo `json:"sections"`
+	// shared libraries the file needs
+	Libraries []string `json:"libraries,omitempty"`
+	// "library!symbol", in the order of the import table; ELF symbols not
+	// bound to a library by symbol versioning have no "library!"
+	Imports []string `json:"imports,omitempty"`
+	Exports []string `json:"exports,omitempty"`
+	// import hash of PE files, for clustering samples built alike
+	ImpHash string `json:"imphash,omitempty"`
+	// the CLR metadata of .NET assemblies
+	DotNet *DotNetInfo `json:"dotnet,omitempty"`
+	// why the file looks packed, see packerHints
+	Packer []string `json:"packer,omitempty"`
+	// when the linker says it built a PE file; reproducible builds leave
+	// a hash or nothing instead
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// the toolchain of a PE file built by Microsoft's linker
+	Rich []RichEntry `json:"rich,omitempty"`
+	// the code signature of PE and Mach-O files, nil when unsigned
+	Signature *SignatureInfo `json:"signature,omitempty"`
+	// data appended to a PE file, see peOverlay
+	Overlay *OverlayInfo `json:"overlay,omitempty"`
+}
+
+// OverlayInfo describes the data after the end of a PE file's image, which
+// the loader ignores: installers keep their archives there and droppers
+// their payloads.
+type OverlayInfo struct {
+	Offset  uint64  `json:"offset"`
+	Size    uint64  `json:"size"`
+	Entropy float64 `json:"entropy"`
+}
+
+// RichEntry counts the objects that one compiler, assembler or linker
+// build contributed to a PE file, as the Rich header after the DOS stub
+// records them.
+type RichEntry struct {
+	Product uint16 `json:"product"`
+	Build   uint16 `json:"build"`
+	Count   uint32 `json:"count"`
+}
+
+// SectionInfo describes one section of an executable.
+type SectionInfo struct {
+	Name   string `json:"name"`
+	Addr   uint64 `json:"addr"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+	Perms  string `json:"perms"` // "rwx" style, "-" for missing permissions
+	// bits per byte of the content in the file, 0 for sections without any
+	Entropy float64 `json:"entropy"`
+	// what is unusual about the section, see measure
+	Flags []string `json:"flags,omitempty"`
+}
+
+// code above this entropy is likely packed or encrypted; compiled code
+// stays around 6
+const packedEntropy = 7.0
+
+// measure sets the entropy of a section from its content and flags
+// sections that are writable and executable, which code rarely needs, and
+// executable sections of packed or encrypted looking content.
+func (s *SectionInfo) measure(data []byte) {
+	if len(data) > 0 {
+		s.Entropy = byteEntropy(string(data))
+	}
+	executable := strings.HasSuffix(s.Perms, "x")
+	if executable && s.Perms[1] == 'w' {
+		s.Flags = append(s.Flags, "writable+executable")
+	}
+	if executable && s.Entropy > packedEntropy {
+		s.Flags = append(s.Flags, "high-entropy")
+	}
+}
+
+// section names packers leave behind
+var packerSections = map[string]string{
+	"UPX0":     "UPX",
+	"UPX1":     "UPX",
+	"UPX2":     "UPX",
+	".aspack":  "ASPack",
+	".adata":   "ASPack",
+	".MPRESS1": "MPRESS",
+	".MPRESS2": "MPRESS",
+	".petite":  "Petite",
+	".nsp0":    "NsPack",
+	".nsp1":    "NsPack",
+	"pec1":     "PECompact",
+	"PEC2":     "PECompact",
+	"PEC2TO":   "PECompact",
+	".themida": "Themida",
+	".vmp0":    "VMProtect",
+	".vmp1":    "VMProtect",
+	".enigma1": "Enigma",
+	"MEW":      "MEW",
+}
+
+// PE files importing this few functions likely resolve the rest at run
+// time, the way unpacking stubs do
+const tinyImportTable = 5
+
+var ErrUnknownFormat = errors.New("not an ELF, PE or Mach-O file")
+
+// ParseFileHeader parses the ELF, PE or Mach-O header of the file at path.
+func ParseFileHeader(path string) (*FileHeader, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h *FileHeader
+	if f, err := elf.NewFile(bytes.NewReader(content)); err == nil {
+		h = parseELFHeader(path, f)
+	} else if f := openPE(content); f != nil {
+		h = parsePEHeader(path, f, content)
+	} else if f, err := macho.NewFile(bytes.NewReader(content)); err == nil {
+		h = parseMachOHeader(path, f, content)
+	} else {
+		return nil, ErrUnknownFormat
+	}
+	h.Packer = packerHints(h)
+	return h, nil
+}
+
+// packerHints lists what suggests that a file is packed: section names of
+// known packers, executable sections of random looking content and, in PE
+// files with code other than .NET assemblies, an import table of a handful
+// of functions or none.
+func packerHints(h *FileHeader) []string {
+	var hints []string
+	code := false
+	for _, s := range h.Sections {
+		code = code || strings.HasSuffix(s.Perms, "x")
+		if packer, ok := packerSections[strings.TrimRight(s.Name, "\x00")]; ok {
+			hint := packer + " section " + s.Name
+			if !slices.Contains(hints, hint) {
+				hints = append(hints, hint)
+			}
+		}
+		if slices.Contains(s.Flags, "high-entropy") {
+			hints = append(hints, "high-entropy code in "+s.Name)
+		}
+	}
+	if h.Format == "PE" && code && h.DotNet == nil && len(h.Imports) <= tinyImportTable {
+		hints = append(hints, fmt.Sprintf("tiny import table (%d imports)", len(h.Imports)))
+	}
+	return hints
+}
+
+func perms(r, w, x bool) string {
+	b := []byte("---")
+	if r {
+		b[0] = 'r'
+	}
+	if w {
+		b[1] = 'w'
+	}
+	if x {
+		b[2] = 'x'
+	}
+	return string(b)
+}
+
+func parseELFHeader(path string, f *elf.File) *FileHeader {
+	h := &FileHeader{
+		Path:   path,
+		Format: "ELF",
+		Arch:   strings.TrimPrefix(f.Machine.String(), "EM_"),
+		Bits:   32,
+		Type:   strings.TrimPrefix(f.Type.String(), "ET_"),
+		Entry:  f.Entry,
+	}
+	if f.Class == elf.ELFCLASS64 {
+		h.Bits = 64
+	}
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NULL {
+			continue
+		}
+		info := SectionInfo{
+			Name:   s.Name,
+			Addr:   s.Addr,
+			Offset: s.Offset,
+			Size:   s.Size,
+			Perms:  perms(s.Flags&elf.SHF_ALLOC != 0, s.Flags&elf.SHF_WRITE != 0, s.Flags&elf.SHF_EXECINSTR != 0),
+		}
+		var data []byte
+		if s.Type != elf.SHT_NOBITS {
+			data, _ = s.Data()
+		}
+		info.measure(data)
+		h.Sections = append(h.Sections, info)
+	}
+
+	// static executables have no dynamic section
+	h.Libraries, _ = f.ImportedLibraries()
+	symbols, _ := f.DynamicSymbols()
+	soname := ""
+	if names, _ := f.DynString(elf.DT_SONAME); len(names) > 0 {
+		soname = names[0] + "!"
+	}
+	for _, sym := range symbols {
+		bind, typ := elf.ST_BIND(sym.Info), elf.ST_TYPE(sym.Info)
+		if sym.Name == "" || (bind != elf.STB_GLOBAL && bind != elf.STB_WEAK) || typ == elf.STT_SECTION || typ == elf.STT_FILE {
+			continue
+		}
+		if sym.Section == elf.SHN_UNDEF {
+			name := sym.Name
+			if sym.Library != "" {
+				name = sym.Library + "!" + name
+			}
+			h.Imports = append(h.Imports, name)
+		} else if sym.Section != elf.SHN_ABS || sym.Value != 0 {
+			// absolute zero symbols name symbol versions, not code or data
+			h.Exports = append(h.Exports, soname+sym.Name)
+		}
+	}
+	return h
+}
+
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "X86_64",
+	pe.IMAGE_FILE_MACHINE_ARM:   "ARM",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "ARM",
+	pe.IMAGE_FILE_MACHINE_ARM64: "AARCH64",
+}
+
+func parsePEHeader(path string, f *pe.File, content []byte) *FileHeader {
+	h := &FileHeader{
+		Path:   path,
+		Format: "PE",
+		Arch:   peMachines[f.Machine],
+		Type:   "EXEC",
+	}
+	if h.Arch == "" {
+		h.Arch = fmt.Sprintf("0x%04x", f.Machine)
+	}
+	if f.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		h.Type = "DLL"
+	}
+	if f.TimeDateStamp != 0 {
+		t := time.Unix(int64(f.TimeDateStamp), 0).UTC()
+		h.Timestamp = &t
+	}
+	// the DOS stub ends where the PE header starts
+	if lfanew := binary.LittleEndian.Uint32(content[0x3c:]); uint64(lfanew) <= uint64(len(content)) {
+		h.Rich = richHeader(content[:lfanew])
+	}
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		h.Bits = 32
+		h.Entry = uint64(oh.ImageBase) + uint64(oh.AddressOfEntryPoint)
+	case *pe.OptionalHeader64:
+		h.Bits = 64
+		h.Entry = oh.ImageBase + uint64(oh.AddressOfEntryPoint)
+	}
+	for _, s := range f.Sections {
+		c := s.Characteristics
+		info := SectionInfo{
+			Name:   s.Name,
+			Addr:   uint64(s.VirtualAddress),
+			Offset: uint64(s.Offset),
+			Size:   uint64(s.Size),
+			Perms:  perms(c&pe.IMAGE_SCN_MEM_READ != 0, c&pe.IMAGE_SCN_MEM_WRITE != 0, c&pe.IMAGE_SCN_MEM_EXECUTE != 0),
+		}
+		data, _ := s.Data()
+		info.measure(data)
+		h.Sections = append(h.Sections, info)
+	}
+
+	img := newPEImage(f)
+	h.Imports = img.imports()
+	h.Exports = img.exports()
+	h.ImpHash = impHash(h.Imports)
+	h.DotNet = img.dotNetInfo()
+	h.Signature = authenticode(f, content)
+	if start, end := peOverlay(f, content); end > start {
+		h.Overlay = &OverlayInfo{Offset: uint64(start), Size: uint64(end - start), Entropy: byteEntropy(string(content[start:end]))}
+	}
+	for _, imp := range h.Imports {
+		dll, _, _ := strings.Cut(imp, "!")
+		if !slices.Contains(h.Libraries, dll) {
+			h.Libraries = append(h.Libraries, dll)
+		}
+	}
+	return h
+}
+
+// richHeader decodes the Rich header in the DOS stub of a PE file: XORed
+// with the key after "Rich", from the "DanS" marker and three words of
+// padding to the "Rich", every tool is its product ID and build followed
+// by the number of objects it made.
+func richHeader(stub []byte) []RichEntry {
+	end := bytes.LastIndex(stub, []byte("Rich"))
+	if end < 0 || end+8 > len(stub) {
+		return nil
+	}
+	key := binary.LittleEndian.Uint32(stub[end+4:])
+	start := -1
+	for i := end - 4; i >= 0; i -= 4 {
+		if binary.LittleEndian.Uint32(stub[i:])^key == 0x536e6144 {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	var entries []RichEntry
+	for i := start + 16; i+8 <= end; i += 8 {
+		id := binary.LittleEndian.Uint32(stub[i:]) ^ key
+		entries = append(entries, RichEntry{
+			Product: uint16(id >> 16),
+			Build:   uint16(id),
+			Count:   binary.LittleEndian.Uint32(stub[i+4:]) ^ key,
+		})
+	}
+	return entries
+}
+
+// peImage reads a PE file by relative virtual address, the way the loader
+// maps it. debug/pe leaves out imports by ordinal and has no exports.
+type peImage struct {
+	sections []peSection
+	pe64     bool
+	dirs     []pe.DataDirectory
+}
+
+type peSection struct {
+	addr uint32
+	data []byte
+}
+
+// the most import or export entries read, against malformed tables
+const maxPESymbols = 1 << 16
+
+func newPEImage(f *pe.File) *peImage {
+	img := &peImage{}
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		img.dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	case *pe.OptionalHeader64:
+		img.pe64 = true
+		img.dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	}
+	for _, s := range f.Sections {
+		data, err := s.Data()
+		if err != nil {
+			continue
+		}
+		img.sections = append(img.sections, peSection{s.VirtualAddress, data})
+	}
+	return img
+}
+
+// openPE parses content as a PE file, nil when it is not one.
+// certificateTable returns the data directory of the Authenticode
+// signature, the one directory holding a file offset rather than an
+// address.
+func certificateTable(f *pe.File) (pe.DataDirectory, bool) {
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		return pe.DataDirectory{}, false
+	}
+	return dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY], true
+}
+
+// peOverlay returns where the overlay of a PE file starts and ends: after
+// the last section and the COFF symbol table MinGW leaves behind it, and
+// before the signature when that comes last. start == end when there is
+// none.
+func peOverlay(f *pe.File, content []byte) (start, end int) {
+	for _, s := range f.Sections {
+		start = max(start, int(s.Offset)+int(s.Size))
+	}
+	if sym := int(f.PointerToSymbolTable); f.NumberOfSymbols > 0 && sym >= start {
+		// the string table follows the symbols, starting with its size
+		strtab := sym + int(f.NumberOfSymbols)*pe.COFFSymbolSize
+		start = max(start, strtab)
+		if strtab+4 <= len(content) {
+			start = max(start, strtab+int(binary.LittleEndian.Uint32(content[strtab:])))
+		}
+	}
+	end = len(content)
+	if table, ok := certificateTable(f); ok && table.Size > 0 && int(table.VirtualAddress)+int(table.Size) == end {
+		end = int(table.VirtualAddress)
+	}
+	return min(start, end), end
+}
+
+func openPE(content []byte) *pe.File {
+	if !bytes.HasPrefix(content, []byte("MZ")) {
+		return nil
+	}
+	f, err := pe.NewFile(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// at returns n bytes at rva, nil when they are not in the file.
+func (img *peImage) at(rva uint32, n int) []byte {
+	for _, s := range img.sections {
+		if rva >= s.addr && uint64(rva-s.addr)+uint64(n) <= uint64(len(s.data)) {
+			return s.data[rva-s.addr : int(rva-s.addr)+n]
+		}
+	}
+	return nil
+}
+
+func (img *peImage) uint32At(rva uint32) (uint32, bool) {
+	b := img.at(rva, 4)
+	if b == nil {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(b), true
+}
+
+// cstring returns the NUL-terminated string at rva.
+func (img *peImage) cstring(rva uint32) string {
+	for _, s := range img.sections {
+		if rva >= s.addr && rva-s.addr < uint32(len(s.data)) {
+			b := s.data[rva-s.addr:]
+			b = b[:min(len(b), 512)]
+			if i := bytes.IndexByte(b, 0); i >= 0 {
+				b = b[:i]
+			}
+			return string(b)
+		}
+	}
+	return ""
+}
+
+func (img *peImage) dir(index int) pe.DataDirectory {
+	if index < len(img.dirs) {
+		return img.dirs[index]
+	}
+	return pe.DataDirectory{}
+}
+
+// imports walks the import directory. Imports by ordinal are named
+// "#ordinal".
+func (img *peImage) imports() []string {
+	var imports []string
+	thunkSize := uint32(4)
+	if img.pe64 {
+		thunkSize = 8
+	}
+	for desc := img.dir(pe.IMAGE_DIRECTORY_ENTRY_IMPORT).VirtualAddress; desc != 0; desc += 20 {
+		b := img.at(desc, 20)
+		if b == nil {
+			break
+		}
+		lookup, name, thunks := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[12:]), binary.LittleEndian.Uint32(b[16:])
+		if lookup == 0 && name == 0 && thunks == 0 {
+			break
+		}
+		// the lookup table, or the address table when a linker left it out
+		if lookup == 0 {
+			lookup = thunks
+		}
+		dll := img.cstring(name)
+		for rva := lookup; len(imports) < maxPESymbols; rva += thunkSize {
+			b := img.at(rva, int(thunkSize))
+			if b == nil {
+				break
+			}
+			var entry uint64
+			var byOrdinal bool
+			if img.pe64 {
+				entry = binary.LittleEndian.Uint64(b)
+				byOrdinal = entry&(1<<63) != 0
+			} else {
+				entry = uint64(binary.LittleEndian.Uint32(b))
+				byOrdinal = entry&(1<<31) != 0
+			}
+			if entry == 0 {
+				break
+			}
+			symbol := fmt.Sprintf("#%d", uint16(entry))
+			if !byOrdinal {
+				// past the two byte hint
+				symbol = img.cstring(uint32(entry) + 2)
+			}
+			imports = append(imports, dll+"!"+symbol)
+		}
+		if len(imports) >= maxPESymbols {
+			break
+		}
+	}
+	return imports
+}
+
+// exports returns the names in the export directory of a DLL; exports by
+// ordinal only have no name and are left out.
+func (img *peImage) exports() []string {
+	dir := img.dir(pe.IMAGE_DIRECTORY_ENTRY_EXPORT)
+	b := img.at(dir.VirtualAddress, 40)
+	if dir.VirtualAddress == 0 || b == nil {
+		return nil
+	}
+	dll := img.cstring(binary.LittleEndian.Uint32(b[12:]))
+	count, names := binary.LittleEndian.Uint32(b[24:]), binary.LittleEndian.Uint32(b[32:])
+	var exports []string
+	for i := uint32(0); i < min(count, maxPESymbols); i++ {
+		name, ok := img.uint32At(names + 4*i)
+		if !ok {
+			break
+		}
+		exports = append(exports, dll+"!"+img.cstring(name))
+	}
+	return exports
+}
+
+// impHash computes the import hash the way pefile and VirusTotal do: the
+// MD5 of "library.symbol" pairs in import order, lower case, with the .dll,
+// .ocx or .sys extension cut off the library. Imports by ordinal become
+// "ordN": pefile's names for the well-known ordinals of ws2_32, wsock32 and
+// oleaut32 are not reproduced, so files importing those hash differently.
+func impHash(imports []string) string {
+	if len(imports) == 0 {
+		return ""
+	}
+	entries := make([]string, len(imports))
+	for i, imp := range imports {
+		dll, symbol, _ := strings.Cut(strings.ToLower(imp), "!")
+		if ext := dll[strings.LastIndexByte(dll, '.')+1:]; ext == "dll" || ext == "ocx" || ext == "sys" {
+			dll = dll[:len(dll)-len(ext)-1]
+		}
+		if ordinal, ok := strings.CutPrefix(symbol, "#"); ok {
+			symbol = "ord" + ordinal
+		}
+		entries[i] = dll + "." + symbol
+	}
+	sum := md5.Sum([]byte(strings.Join(entries, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseMachOHeader(path string, f *macho.File, content []byte) *FileHeader {
+	h := &FileHeader{
+		Path:   path,
+		Format: "Mach-O",
+		Arch:   strings.TrimPrefix(f.Cpu.String(), "Cpu"),
+		Bits:   32,
+		Type:   strings.TrimPrefix(f.Type.String(), "Type"),
+	}
+	if f.Magic == macho.Magic64 {
+		h.Bits = 64
+	}
+	for _, s := range f.Sections {
+		// Mach-O keeps protections on the segment, not the section
+		var prot uint32
+		if seg := f.Segment(s.Seg); seg != nil {
+			prot = seg.Prot
+		}
+		info := SectionInfo{
+			Name:   s.Seg + "," + s.Name,
+			Addr:   s.Addr,
+			Offset: uint64(s.Offset),
+			Size:   s.Size,
+			Perms:  perms(prot&1 != 0, prot&2 != 0, prot&4 != 0),
+		}
+		var data []byte
+		// zero fill sections have nothing in the file
+		if s.Flags&0xff != 1 {
+			data, _ = s.Data()
+		}
+		info.measure(data)
+		h.Sections = append(h.Sections, info)
+	}
+	h.Signature = machOSignature(f, content)
+	return h
+}
+
+// FormatHeader renders a header as human readable text.
+func FormatHeader(h *FileHeader) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", h.Path)
+	fmt.Fprintf(&b, "  Format: %s (%d-bit)\n", h.Format, h.Bits)
+	fmt.Fprintf(&b, "  Arch:   %s\n", h.Arch)
+	fmt.Fprintf(&b, "  Type:   %s\n", h.Type)
+	if h.Entry != 0 {
+		fmt.Fprintf(&b, "  Entry:  0x%x\n", h.Entry)
+	}
+	if h.Timestamp != nil {
+		fmt.Fprintf(&b, "  Linked: %s\n", h.Timestamp.Format(time.DateTime+" MST"))
+	}
+	if len(h.Sections) > 0 {
+		fmt.Fprintf(&b, "  Sections:\n")
+		fmt.Fprintf(&b, "    %-24s %-5s %18s %10s %10s %7s\n", "NAME", "PERMS", "ADDR", "OFFSET", "SIZE", "ENTROPY")
+		for _, s := range h.Sections {
+			line := fmt.Sprintf("    %-24s %-5s %#18x %#10x %10d %7.2f", s.Name, s.Perms, s.Addr, s.Offset, s.Size, s.Entropy)
+			if len(s.Flags) > 0 {
+				line += "  [" + strings.Join(s.Flags, ", ") + "]"
+			}
+			fmt.Fprintln(&b, line)
+		}
+	}
+	if o := h.Overlay; o != nil {
+		fmt.Fprintf(&b, "  Overlay: %d bytes at %#x, entropy %.2f\n", o.Size, o.Offset, o.Entropy)
+	}
+	if len(h.Libraries) > 0 {
+		fmt.Fprintf(&b, "  Libraries: %s\n", strings.Join(h.Libraries, ", "))
+	}
+	if h.ImpHash != "" {
+		fmt.Fprintf(&b, "  Imphash: %s\n", h.ImpHash)
+	}
+	if len(h.Rich) > 0 {
+		fmt.Fprintf(&b, "  Rich header (%d):\n", len(h.Rich))
+		fmt.Fprintf(&b, "    %-7s %5s %7s\n", "PRODUCT", "BUILD", "COUNT")
+		for _, e := range h.Rich {
+			fmt.Fprintf(&b, "    %#7x %5d %7d\n", e.Product, e.Build, e.Count)
+		}
+	}
+	if sig := h.Signature; sig != nil {
+		fmt.Fprintf(&b, "  Signature: %s", sig.Kind)
+		if sig.Signer != "" {
+			fmt.Fprintf(&b, " by %q, issued by %q, valid %s to %s", sig.Signer, sig.Issuer, sig.NotBefore.Format(time.DateOnly), sig.NotAfter.Format(time.DateOnly))
+		}
+		if sig.DigestMatches {
+			b.WriteString(", digest matches the file")
+		} else {
+			b.WriteString(", digest does not match the file")
+		}
+		if len(sig.Flags) > 0 {
+			b.WriteString("  [" + strings.Join(sig.Flags, ", ") + "]")
+		}
+		b.WriteString("\n")
+	} else if h.Format == "PE" || h.Format == "Mach-O" {
+		fmt.Fprintf(&b, "  Signature: none\n")
+	}
+	if len(h.Packer) > 0 {
+		fmt.Fprintf(&b, "  Packer hints: %s\n", strings.Join(h.Packer, ", "))
+	}
+	if d := h.DotNet; d != nil {
+		fmt.Fprintf(&b, "  .NET:   runtime %s", d.Runtime)
+		if d.Assembly != "" {
+			fmt.Fprintf(&b, ", assembly %s %s", d.Assembly, d.Version)
+		}
+		if d.Framework != "" {
+			fmt.Fprintf(&b, ", %s", d.Framework)
+		}
+		fmt.Fprintln(&b)
+	}
+	for _, list := range []struct {
+		title   string
+		symbols []string
+	}{{"Imports", h.Imports}, {"Exports", h.Exports}} {
+		if len(list.symbols) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s (%d):\n", list.title, len(list.symbols))
+		for _, sym := range list.symbols {
+			fmt.Fprintf(&b, "    %s\n", sym)
+		}
+	}
+	if h.DotNet != nil && len(h.DotNet.UserStrings) > 0 {
+		// quoted, they are text of any kind
+		fmt.Fprintf(&b, "  User strings (%d):\n", len(h.DotNet.UserStrings))
+		for _, s := range h.DotNet.UserStrings {
+			fmt.Fprintf(&b, "    %q\n", s)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,450 @@
+package flagrep
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/md5"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"debug/pe"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFileHeader(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	h, err := ParseFileHeader(exe)
+	if err != nil {
+		t.Fatalf("parsing the test binary: %v", err)
+	}
+	if h.Format == "" || h.Arch == "" || len(h.Sections) == 0 {
+		t.Errorf("incomplete header: %+v", h)
+	}
+
+	executable := false
+	for _, s := range h.Sections {
+		if strings.HasSuffix(s.Perms, "x") {
+			executable = true
+			if s.Entropy < 4 || s.Entropy > packedEntropy || len(s.Flags) > 0 {
+				t.Errorf("unexpected code section: %+v", s)
+			}
+		}
+	}
+	if !executable {
+		t.Error("expected at least one executable section")
+	}
+}
+
+func TestSectionMeasure(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.NewChaCha8([32]byte{}).Read(random)
+
+	packed := SectionInfo{Name: "UPX1", Perms: "rwx"}
+	packed.measure(random)
+	if packed.Entropy < 7.9 || !slices.Equal(packed.Flags, []string{"writable+executable", "high-entropy"}) {
+		t.Errorf("got %+v", packed)
+	}
+	if !strings.Contains(FormatHeader(&FileHeader{Sections: []SectionInfo{packed}}), "[writable+executable, high-entropy]") {
+		t.Error("flags missing from the text output")
+	}
+
+	// random data is only suspicious where it gets executed
+	data := SectionInfo{Name: ".rsrc", Perms: "r--"}
+	data.measure(random)
+	bss := SectionInfo{Name: ".bss", Perms: "rw-"}
+	bss.measure(nil)
+	if len(data.Flags) > 0 || bss.Entropy != 0 || len(bss.Flags) > 0 {
+		t.Errorf("got %+v and %+v", data, bss)
+	}
+}
+
+func TestParseELFSymbols(t *testing.T) {
+	// a C++ shared library from the Go distribution
+	h, err := ParseFileHeader(filepath.Join(runtime.GOROOT(), "src", "debug", "elf", "testdata", "libtiffxx.so_"))
+	if err != nil {
+		t.Skip(err)
+	}
+	if !slices.Equal(h.Libraries, []string{"libtiff.so.6", "libstdc++.so.6", "libc.so.6"}) {
+		t.Errorf("got libraries %v", h.Libraries)
+	}
+	if !slices.Contains(h.Imports, "libtiff.so.6!TIFFClientOpen") || !slices.Contains(h.Imports, "__gmon_start__") {
+		t.Errorf("unexpected imports: %v", h.Imports)
+	}
+	if !slices.Equal(h.Exports, []string{"libtiffxx.so.6!_Z14TIFFStreamOpenPKcPSo", "libtiffxx.so.6!_Z14TIFFStreamOpenPKcPSi"}) {
+		t.Errorf("got exports %v", h.Exports)
+	}
+	if !slices.ContainsFunc(h.Sections, func(s SectionInfo) bool { return s.Name == ".dynsym" }) {
+		t.Errorf("section names not resolved: %+v", h.Sections)
+	}
+}
+
+func TestParsePEImports(t *testing.T) {
+	// a MinGW hello world from the Go distribution
+	h, err := ParseFileHeader(filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Skip(err)
+	}
+	if len(h.Imports) != 32 || h.Imports[0] != "KERNEL32.dll!DeleteCriticalSection" || !slices.Contains(h.Imports, "msvcrt.dll!puts") {
+		t.Errorf("unexpected imports: %v", h.Imports)
+	}
+	if !slices.Equal(h.Libraries, []string{"KERNEL32.dll", "msvcrt.dll"}) {
+		t.Errorf("got libraries %v", h.Libraries)
+	}
+	if h.ImpHash != "afe54265af0ee5570cb87b013a2f38da" {
+		t.Errorf("got imphash %s", h.ImpHash)
+	}
+	if !strings.Contains(FormatHeader(h), "Imports (32):") {
+		t.Errorf("imports missing from:\n%s", FormatHeader(h))
+	}
+}
+
+func TestPEImageImportsExports(t *testing.T) {
+	// one section at 0x1000 holding an import and an export directory
+	data := make([]byte, 0x200)
+	put := func(off int, v uint32) { binary.LittleEndian.PutUint32(data[off:], v) }
+	copy(data[0x100:], "demo.dll\x00")
+	copy(data[0x110:], "\x00\x00Run\x00")
+	copy(data[0x120:], "ws2_32.dll\x00")
+	copy(data[0x130:], "Start\x00")
+	// import descriptor and lookup table: Run by name, then ordinal 23
+	put(0x00, 0x1040)
+	put(0x0c, 0x1120)
+	put(0x10, 0x1040)
+	put(0x40, 0x1110)
+	put(0x44, 1<<31|23)
+	// export directory with one name
+	put(0x60+12, 0x1100)
+	put(0x60+24, 1)
+	put(0x60+32, 0x10a0)
+	put(0xa0, 0x1130)
+
+	img := &peImage{
+		sections: []peSection{{0x1000, data}},
+		dirs:     make([]pe.DataDirectory, 16),
+	}
+	img.dirs[pe.IMAGE_DIRECTORY_ENTRY_IMPORT].VirtualAddress = 0x1000
+	img.dirs[pe.IMAGE_DIRECTORY_ENTRY_EXPORT].VirtualAddress = 0x1060
+
+	imports := img.imports()
+	if !slices.Equal(imports, []string{"ws2_32.dll!Run", "ws2_32.dll!#23"}) {
+		t.Errorf("got imports %v", imports)
+	}
+	if exports := img.exports(); !slices.Equal(exports, []string{"demo.dll!Start"}) {
+		t.Errorf("got exports %v", exports)
+	}
+	sum := md5.Sum([]byte("ws2_32.run,ws2_32.ord23"))
+	if got := impHash(imports); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("got imphash %s", got)
+	}
+}
+
+func TestDotNetInfo(t *testing.T) {
+	le := binary.LittleEndian
+	// metadata with a Module and an Assembly row
+	tables := make([]byte, 24)
+	tables[4] = 2
+	le.PutUint64(tables[8:], 1<<mdModule|1<<mdAssembly)
+	tables = le.AppendUint32(tables, 1)
+	tables = le.AppendUint32(tables, 1)
+	tables = append(tables, make([]byte, 10)...)
+	tables = le.AppendUint32(tables, 0x8004)
+	for _, v := range []uint16{1, 2, 3, 4} {
+		tables = le.AppendUint16(tables, v)
+	}
+	tables = le.AppendUint32(tables, 0)
+	tables = le.AppendUint16(tables, 0)
+	tables = le.AppendUint16(tables, 10)
+	tables = le.AppendUint16(tables, 0)
+	framework := ".NETFramework,Version=v4.8"
+	streams := []struct {
+		name string
+		data []byte
+	}{
+		{"#~", tables},
+		{"#Strings", []byte("\x00<Module>\x00Demo\x00\x00\x00")},
+		{"#US", []byte("\x00\x0bh\x00e\x00l\x00l\x00o\x00\x00\x00")},
+		{"#Blob", append([]byte{0, byte(len(framework) + 5), 1, 0, byte(len(framework))}, framework+"\x00\x00\x00\x00"...)},
+	}
+	md := []byte("BSJB\x01\x00\x01\x00\x00\x00\x00\x00\x0c\x00\x00\x00v4.0.30319\x00\x00\x00\x00\x04\x00")
+	headers := 0
+	for _, s := range streams {
+		headers += 8 + (len(s.name)+4)&^3
+	}
+	off := len(md) + headers
+	for _, s := range streams {
+		md = le.AppendUint32(md, uint32(off))
+		md = le.AppendUint32(md, uint32(len(s.data)))
+		md = append(md, s.name...)
+		md = append(md, make([]byte, (len(s.name)+4)&^3-len(s.name))...)
+		off += len(s.data)
+	}
+	for _, s := range streams {
+		md = append(md, s.data...)
+	}
+
+	// the CLR header points at the metadata after it
+	data := make([]byte, 0x100, 0x100+len(md))
+	le.PutUint32(data[0:], 72)
+	le.PutUint32(data[8:], 0x2100)
+	le.PutUint32(data[12:], uint32(len(md)))
+	data = append(data, md...)
+	img := &peImage{
+		sections: []peSection{{0x2000, data}},
+		dirs:     make([]pe.DataDirectory, 16),
+	}
+	img.dirs[pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR].VirtualAddress = 0x2000
+
+	info := img.dotNetInfo()
+	if info == nil {
+		t.Fatal("no .NET metadata found")
+	}
+	want := DotNetInfo{Runtime: "v4.0.30319", Assembly: "Demo", Version: "1.2.3.4", Framework: framework, UserStrings: []string{"hello"}}
+	if info.Runtime != want.Runtime || info.Assembly != want.Assembly || info.Version != want.Version ||
+		info.Framework != want.Framework || !slices.Equal(info.UserStrings, want.UserStrings) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+	if !strings.Contains(FormatHeader(&FileHeader{DotNet: info}), "assembly Demo 1.2.3.4, .NETFramework,Version=v4.8") {
+		t.Errorf(".NET details missing from:\n%s", FormatHeader(&FileHeader{DotNet: info}))
+	}
+}
+
+func TestPackerHints(t *testing.T) {
+	packed := &FileHeader{Format: "PE", Sections: []SectionInfo{
+		{Name: "UPX0", Perms: "rwx"},
+		{Name: "UPX1", Perms: "rwx", Flags: []string{"writable+executable", "high-entropy"}},
+	}, Imports: []string{"KERNEL32.DLL!LoadLibraryA", "KERNEL32.DLL!GetProcAddress"}}
+	want := []string{"UPX section UPX0", "UPX section UPX1", "high-entropy code in UPX1", "tiny import table (2 imports)"}
+	if got := packerHints(packed); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// a .NET assembly imports just _CorExeMain, resource DLLs have no code
+	dotnet := &FileHeader{Format: "PE", Sections: []SectionInfo{{Name: ".text", Perms: "r-x"}}, Imports: []string{"mscoree.dll!_CorExeMain"}, DotNet: &DotNetInfo{}}
+	resources := &FileHeader{Format: "PE", Sections: []SectionInfo{{Name: ".rsrc", Perms: "r--"}}}
+	if hints := append(packerHints(dotnet), packerHints(resources)...); len(hints) > 0 {
+		t.Errorf("unexpected hints %q", hints)
+	}
+
+	h, err := ParseFileHeader(filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Skip(err)
+	}
+	if len(h.Packer) > 0 {
+		t.Errorf("hello world looks packed: %q", h.Packer)
+	}
+}
+
+func TestRichHeader(t *testing.T) {
+	const key = 0x5a5a1234
+	le := binary.LittleEndian
+	stub := make([]byte, 0x80)
+	for _, v := range []uint32{0x536e6144, 0, 0, 0, 0x01047299, 12, 0x00010000, 3} {
+		stub = le.AppendUint32(stub, v^key)
+	}
+	stub = append(stub, "Rich"...)
+	stub = le.AppendUint32(stub, key)
+	stub = append(stub, make([]byte, 8)...)
+
+	want := []RichEntry{{Product: 0x104, Build: 29337, Count: 12}, {Product: 1, Build: 0, Count: 3}}
+	if got := richHeader(stub); !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := richHeader(stub[:0x80]); got != nil {
+		t.Errorf("found %+v in a stub without one", got)
+	}
+
+	h, err := ParseFileHeader(filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Skip(err)
+	}
+	if h.Timestamp == nil || h.Timestamp.Unix() != 1282022240 || len(h.Rich) > 0 {
+		t.Errorf("got timestamp %v and Rich header %+v from MinGW", h.Timestamp, h.Rich)
+	}
+}
+
+func TestAuthenticode(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Skip(err)
+	}
+	content = append(content, make([]byte, -len(content)&7)...)
+	le := binary.LittleEndian
+	optional := int(le.Uint32(content[0x3c:])) + 24
+	checksum, entry := optional+64, optional+96+8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+	digest := sha256.New()
+	digest.Write(content[:checksum])
+	digest.Write(content[checksum+4 : entry])
+	digest.Write(content[entry+8:])
+
+	pub, priv, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Test Signer"},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	raw, err := x509.CreateCertificate(crand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	marshal := func(v any) []byte {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	explicit := func(b []byte) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b}
+	}
+	var indirect spcIndirectData
+	indirect.Data = asn1.NullRawValue
+	indirect.Digest.Algorithm.Algorithm = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	indirect.Digest.Digest = digest.Sum(nil)
+	var signer pkcs7SignerInfo
+	signer.Version = 1
+	signer.IssuerAndSerial.Issuer = asn1.RawValue{FullBytes: cert.RawIssuer}
+	signer.IssuerAndSerial.SerialNumber = cert.SerialNumber
+	der := marshal(contentInfo{
+		ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2},
+		Content: explicit(marshal(struct {
+			Version          int
+			DigestAlgorithms asn1.RawValue
+			ContentInfo      contentInfo
+			Certificates     asn1.RawValue
+			SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+		}{
+			Version:          1,
+			DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+			ContentInfo: contentInfo{
+				ContentType: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4},
+				Content:     explicit(marshal(indirect)),
+			},
+			Certificates: explicit(cert.Raw),
+			SignerInfos:  []pkcs7SignerInfo{signer},
+		})),
+	})
+
+	// WIN_CERTIFICATE, padded to eight bytes
+	start := len(content)
+	content = le.AppendUint32(content, uint32(8+len(der)))
+	content = le.AppendUint16(content, 0x0200)
+	content = le.AppendUint16(content, 2)
+	content = append(content, der...)
+	content = append(content, make([]byte, -len(content)&7)...)
+	le.PutUint32(content[entry:], uint32(start))
+	le.PutUint32(content[entry+4:], uint32(len(content)-start))
+
+	sig := authenticode(openPE(content), content)
+	if sig == nil || !sig.DigestMatches || sig.Signer != "Test Signer" || !sig.NotAfter.Equal(template.NotAfter) || len(sig.Flags) > 0 {
+		t.Fatalf("got %+v", sig)
+	}
+	appended := append(slices.Clip(content), "flag{hidden}"...)
+	if sig := authenticode(openPE(appended), appended); !sig.DigestMatches || !slices.Equal(sig.Flags, []string{"data after the signature"}) {
+		t.Errorf("with appended data got %+v", sig)
+	}
+	content[0x50] ^= 1
+	if sig := authenticode(openPE(content), content); sig.DigestMatches {
+		t.Errorf("digest matches a modified file")
+	}
+}
+
+func TestCodeDirectoryMatches(t *testing.T) {
+	content := make([]byte, 5000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	cd := make([]byte, 48)
+	binary.BigEndian.PutUint32(cd, csCodeDirectory)
+	binary.BigEndian.PutUint32(cd[16:], 48) // hash offset
+	binary.BigEndian.PutUint32(cd[28:], 2)  // code slots
+	binary.BigEndian.PutUint32(cd[32:], uint32(len(content)))
+	cd[36], cd[37], cd[39] = sha256.Size, 2, 12
+	for page := range slices.Chunk(content, 4096) {
+		sum := sha256.Sum256(page)
+		cd = append(cd, sum[:]...)
+	}
+	if !codeDirectoryMatches(cd, content, uint64(len(content))) {
+		t.Error("page hashes do not match")
+	}
+	content[4500] ^= 1
+	if codeDirectoryMatches(cd, content, uint64(len(content))) {
+		t.Error("page hashes match a modified file")
+	}
+}
+
+func TestPEOverlay(t *testing.T) {
+	exe, err := os.ReadFile(filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Skip(err)
+	}
+	// MinGW's symbol table comes after the sections but is no overlay
+	if start, end := peOverlay(openPE(exe), exe); start != end {
+		t.Fatalf("overlay at %d-%d of a plain build", start, end)
+	}
+
+	payload := "flag{in_plain_sight}" + hex.EncodeToString([]byte("flag{in_the_overlay}"))
+	content := append(slices.Clip(exe), payload...)
+	path := filepath.Join(t.TempDir(), "dropper.exe")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ParseFileHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := h.Overlay; o == nil || o.Offset != uint64(len(exe)) || o.Size != uint64(len(payload)) || o.Entropy < 3 {
+		t.Errorf("got overlay %+v", h.Overlay)
+	}
+
+	// a signature at the end is not part of the overlay
+	signed := append(slices.Clip(content), make([]byte, 16)...)
+	optional := int(binary.LittleEndian.Uint32(signed[0x3c:])) + 24
+	entry := optional + 96 + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+	binary.LittleEndian.PutUint32(signed[entry:], uint32(len(content)))
+	binary.LittleEndian.PutUint32(signed[entry+4:], 16)
+	if start, end := peOverlay(openPE(signed), signed); start != len(exe) || end != len(content) {
+		t.Errorf("overlay of a signed file at %d-%d, want %d-%d", start, end, len(exe), len(content))
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), content, path)
+	var got []string
+	for _, m := range out.matches {
+		got = append(got, fmt.Sprintf("%s %d %v", m.Field, m.Offset, m.Decoders))
+	}
+	want := []string{"overlay 0 []", "overlay 0 [hex_without_spaces]"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got matches %q, want %q", got, want)
+	}
+}
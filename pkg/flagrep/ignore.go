@@ -0,0 +1,79 @@
+package flagrep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// default ignore file, looked up in the working directory
+const IgnoreFileName = ".flagrepignore"
+
+// IgnoreList silences known-good matches. Each line of an ignore file is
+// either a match fingerprint (as printed in -json output) or a regular
+// expression tested against the match and its context. Blank lines and lines
+// starting with # are skipped.
+type IgnoreList struct {
+	fingerprints map[string]struct{}
+	patterns     []*regexp.Regexp
+}
+
+func (l *IgnoreList) Contains(m Match) bool {
+	if l == nil {
+		return false
+	}
+	if _, ok := l.fingerprints[Fingerprint(m)]; ok {
+		return true
+	}
+	context := m.Before + m.Text + m.After
+	for _, re := range l.patterns {
+		if re.MatchString(context) {
+			return true
+		}
+	}
+	return false
+}
+
+func LoadIgnoreFile(path string) (*IgnoreList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &IgnoreList{fingerprints: make(map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if isFingerprint(line) {
+			list.fingerprints[strings.ToLower(line)] = struct{}{}
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		list.patterns = append(list.patterns, re)
+	}
+	return list, scanner.Err()
+}
+
+// isFingerprint reports whether s looks like a hex encoded SHA-256.
+func isFingerprint(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package flagrep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// scanJSONLines treats content as JSON Lines, such as exported logs, and
+// searches only the JSONFields of each record. A field path like
+// "request.body" descends into nested objects; when the field holds an
+// object or array, every string inside it is searched. Lines that are not
+// JSON objects are searched whole.
+func (s *Searcher) scanJSONLines(ctx context.Context, content []byte, path string) {
+	fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+	record := 0
+	for line := range bytes.Lines(content) {
+		record++
+		if ctx.Err() != nil {
+			return
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var obj map[string]any
+		if err := json.Unmarshal(line, &obj); err != nil {
+			s.Logger.Debug("not a JSON record", "path", path, "record", record, "err", err)
+			s.searchFrom(ctx, string(line), origin{path: path, line: record, record: record, fileHash: fileHash})
+			continue
+		}
+		for _, field := range s.JSONFields {
+			value, ok := lookupField(obj, field)
+			if !ok {
+				continue
+			}
+			for _, leaf := range stringLeaves(field, value) {
+				s.searchFrom(ctx, leaf.text, origin{path: path, line: record, record: record, field: leaf.path, fileHash: fileHash})
+			}
+		}
+	}
+}
+
+// lookupField finds the value at a dot-separated path in obj. Keys that
+// contain dots themselves, like "log.level", are matched as a whole first.
+func lookupField(obj map[string]any, path string) (any, bool) {
+	if value, ok := obj[path]; ok {
+		return value, true
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] != '.' {
+			continue
+		}
+		inner, ok := obj[path[:i]].(map[string]any)
+		if !ok {
+			continue
+		}
+		if value, ok := lookupField(inner, path[i+1:]); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+type jsonLeaf struct {
+	path, text string
+}
+
+// stringLeaves returns the strings in value with their paths below prefix,
+// in document order for arrays and key order for objects.
+func stringLeaves(prefix string, value any) []jsonLeaf {
+	var leaves []jsonLeaf
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case string:
+			leaves = append(leaves, jsonLeaf{path, v})
+		case []any:
+			for i, elem := range v {
+				walk(path+"["+strconv.Itoa(i)+"]", elem)
+			}
+		case map[string]any:
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(path+"."+key, v[key])
+			}
+		}
+	}
+	walk(prefix, value)
+	return leaves
+}
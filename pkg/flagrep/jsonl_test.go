@@ -0,0 +1,53 @@
+package flagrep
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("flag{nested}"))
+	content := `{"message":"flag{msg}","other":"flag{ignored}"}
+not json flag{raw}
+
+{"log.level":"info","request":{"body":["x","` + encoded + `"]}}
+`
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 10, false)
+	searcher.JSONFields = []string{"message", "request.body"}
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), []byte(content), "log.jsonl")
+
+	want := map[string]int{"message": 1, "": 2, "request.body[1]": 4}
+	got := make(map[string]int)
+	for _, m := range out.matches {
+		if m.After == "ignored}" {
+			t.Errorf("matched a field that was not selected: %+v", m)
+		}
+		if m.Line != m.Record {
+			t.Errorf("line %d, record %d", m.Line, m.Record)
+		}
+		got[m.Field] = m.Record
+	}
+	for field, record := range want {
+		if got[field] != record {
+			t.Errorf("field %q: record %d, want %d", field, got[field], record)
+		}
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	obj := map[string]any{
+		"log.level": "info",
+		"log":       map[string]any{"origin": map[string]any{"file": "main.go"}},
+	}
+	for path, want := range map[string]any{"log.level": "info", "log.origin.file": "main.go"} {
+		if got, ok := lookupField(obj, path); !ok || got != want {
+			t.Errorf("lookupField(%q) = %v, %v", path, got, ok)
+		}
+	}
+	if _, ok := lookupField(obj, "log.missing"); ok {
+		t.Error("found a missing field")
+	}
+}
@@ -0,0 +1,114 @@
+package flagrep
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// literalMatcher finds a fixed pattern without the regexp engine. Most
+// searches are for a literal, and depth-0 scans of large trees spend their
+// time matching it.
+type literalMatcher struct {
+	pattern string
+	// with fold, pattern is lower case ASCII and matched ignoring ASCII case
+	fold bool
+	// Unicode also folds k onto KELVIN SIGN and s onto LONG S; content with
+	// those goes through the regexp the pattern would otherwise compile to
+	otherFolds bool
+	re         func() *regexp.Regexp
+}
+
+// newLiteralMatcher returns nil when pattern needs the regexp engine after
+// all: it is empty, or is to be matched ignoring case but is not ASCII.
+func newLiteralMatcher(pattern string, caseSensitive bool) *literalMatcher {
+	if pattern == "" {
+		return nil
+	}
+	if caseSensitive {
+		return &literalMatcher{pattern: pattern}
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] >= 0x80 {
+			return nil
+		}
+	}
+	lower := strings.ToLower(pattern)
+	return &literalMatcher{
+		pattern:    lower,
+		fold:       true,
+		otherFolds: strings.ContainsAny(lower, "ks"),
+		re: sync.OnceValue(func() *regexp.Regexp {
+			return regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+		}),
+	}
+}
+
+func (l *literalMatcher) useRegexp(content string) bool {
+	return l.otherFolds && (strings.Contains(content, "K") || strings.Contains(content, "ſ"))
+}
+
+func (l *literalMatcher) index(content string) int {
+	if l.fold {
+		return indexASCIIFold(content, l.pattern)
+	}
+	return strings.Index(content, l.pattern)
+}
+
+func (l *literalMatcher) match(content string) bool {
+	if l.useRegexp(content) {
+		return l.re().MatchString(content)
+	}
+	return l.index(content) >= 0
+}
+
+// findAll is regexp.FindAllStringIndex for the literal.
+func (l *literalMatcher) findAll(content string, n int) [][]int {
+	if l.useRegexp(content) {
+		return l.re().FindAllStringIndex(content, n)
+	}
+	var locs [][]int
+	for from := 0; n < 0 || len(locs) < n; {
+		i := l.index(content[from:])
+		if i < 0 {
+			break
+		}
+		start := from + i
+		from = start + len(l.pattern)
+		locs = append(locs, []int{start, from})
+	}
+	return locs
+}
+
+// indexASCIIFold is strings.Index ignoring ASCII case; lower is lower case.
+func indexASCIIFold(s, lower string) int {
+	first := string(lower[0])
+	if c := lower[0]; c >= 'a' && c <= 'z' {
+		first += string(c - 'a' + 'A')
+	}
+	last := len(s) - len(lower)
+	for i := 0; i <= last; i++ {
+		j := strings.IndexAny(s[i:last+1], first)
+		if j < 0 {
+			return -1
+		}
+		i += j
+		if equalASCIIFold(s[i:i+len(lower)], lower) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalASCIIFold(s, lower string) bool {
+	for i := 0; i < len(lower); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package flagrep
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLiteralMatcher(t *testing.T) {
+	contents := []string{
+		"", "flag", "FLAG{x} and flag{y} and FlAg{z}", "flflag", "aaaa", "xx\xffFLAG\x00",
+		"secret SECRET Key", "ſecret Secret", "keyKEYKey", "\u212aey and key", "no match here",
+	}
+	for _, pattern := range []string{"flag", "FLAG{", "aa", "key", "secret", "x"} {
+		for _, caseSensitive := range []bool{true, false} {
+			l := newLiteralMatcher(pattern, caseSensitive)
+			prefix := ""
+			if !caseSensitive {
+				prefix = "(?i)"
+			}
+			re := regexp.MustCompile(prefix + regexp.QuoteMeta(pattern))
+			for _, content := range contents {
+				want := re.FindAllStringIndex(content, -1)
+				if got := l.findAll(content, -1); !slices.EqualFunc(got, want, slices.Equal) {
+					t.Errorf("%q (case sensitive %v) in %q: got %v, want %v", pattern, caseSensitive, content, got, want)
+				}
+				if got := l.match(content); got != (want != nil) {
+					t.Errorf("%q (case sensitive %v) match %q = %v", pattern, caseSensitive, content, got)
+				}
+			}
+		}
+	}
+
+	if l := newLiteralMatcher("flag", true); len(l.findAll("flag flag flag", 2)) != 2 {
+		t.Error("findAll ignores the limit")
+	}
+	if newLiteralMatcher("", true) != nil || newLiteralMatcher("ünï", false) != nil {
+		t.Error("expected the regexp engine for empty and non-ASCII case-insensitive patterns")
+	}
+}
+
+func BenchmarkLiteralMatch(b *testing.B) {
+	content := strings.Repeat("lorem ipsum dolor sit amet, consectetur adipiscing elit ", 20000) + "FLAG{end}"
+	for _, caseSensitive := range []bool{true, false} {
+		name := "case_sensitive"
+		prefix := ""
+		if !caseSensitive {
+			name, prefix = "ignore_case", "(?i)"
+		}
+		b.Run(name+"/literal", func(b *testing.B) {
+			l := newLiteralMatcher("FLAG{", caseSensitive)
+			for b.Loop() {
+				l.match(content)
+			}
+		})
+		b.Run(name+"/regexp", func(b *testing.B) {
+			re := regexp.MustCompile(prefix + regexp.QuoteMeta("FLAG{"))
+			for b.Loop() {
+				re.MatchString(content)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package flagrep
+
+import "time"
+
+// Match is a single hit of the pattern in a (possibly decoded) file.
+type Match struct {
+	File     string
+	FileHash string
+	Decoders []string
+	// other decoder chains that produced the same content
+	Alternatives [][]string
+	Pattern      string // the literal or regular expression searched
+	Preset       string // the preset Pattern comes from, if any
+	Rule         string // YARA rule or built-in detector, "" for pattern matches
+	Detail       string // what the detector found out, e.g. certificate subject
+	Text         string
+	Offset       int    // offset of the match in the decoded content
+	SourceOffset int    // offset in the content before decoding, traced back through the decoders
+	Line         int    // 1-based line in the original file, 0 for decoded matches outside records
+	Record       int    // 1-based line of a JSON Lines record, EventRecordID in event logs
+	Field        string // JSON field path, registry key and value name, event ID and data name, or APK component
+	Before       string
+	After        string
+	Confidence   float64 // see confidence()
+	Steps        []Step  // with Searcher.Explain, how the decoders got to the match
+}
+
+// ScanInfo describes a scan before it starts.
+type ScanInfo struct {
+	// the literal or regular expression searched
+	Pattern string
+	// the preset it comes from, if any
+	Preset        string
+	Paths         []string
+	Recursive     bool
+	CaseSensitive bool
+	Workers       int // 0 for automatic
+	Depth         int
+	ContextBefore int
+	ContextAfter  int
+	Started       time.Time
+}
+
+// ScanSummary describes a finished scan.
+type ScanSummary struct {
+	FilesScanned int64
+	Matches      int64
+	Duration     time.Duration
+	// per worker, nil when only stdin was read
+	Workers []WorkerStats
+	// "max-results" or "max-time" when that limit ended the scan early
+	StoppedBy string
+}
+
+// Output renders scan events. Implementations must be safe for concurrent use.
+type Output interface {
+	Begin(info ScanInfo)
+	Match(m Match)
+	// called when a decoded state had more matches than we report
+	Truncated(file string, decoders []string)
+	End(summary ScanSummary)
+}
+
+// discardOutput is the Output of a Searcher nobody has given one.
+type discardOutput struct{}
+
+func (discardOutput) Begin(info ScanInfo)                      {}
+func (discardOutput) Match(m Match)                            {}
+func (discardOutput) Truncated(file string, decoders []string) {}
+func (discardOutput) End(summary ScanSummary)                  {}
@@ -0,0 +1,60 @@
+package flagrep
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics counts the work done by any number of Searchers sharing it, for
+// monitoring long-running services. A nil *Metrics counts nothing.
+type Metrics struct {
+	files atomic.Int64
+	bytes atomic.Int64
+
+	mu sync.Mutex
+	// decoder applications, successful or not, by decoder name
+	decodes map[string]int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{decodes: make(map[string]int64)}
+}
+
+// FilesScanned is the number of files (or stdin and uploads) searched.
+func (m *Metrics) FilesScanned() int64 {
+	return m.files.Load()
+}
+
+// BytesScanned is the size of every searched file, before decoding.
+func (m *Metrics) BytesScanned() int64 {
+	return m.bytes.Load()
+}
+
+// DecodeAttempts returns a copy of the per-decoder attempt counts.
+func (m *Metrics) DecodeAttempts() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.decodes)
+}
+
+func (m *Metrics) addFile(size int) {
+	if m == nil {
+		return
+	}
+	m.files.Add(1)
+	m.bytes.Add(int64(size))
+}
+
+// addDecodes merges the counts of one search, so the lock is not taken for
+// every decoder call.
+func (m *Metrics) addDecodes(attempts map[string]int64) {
+	if m == nil || len(attempts) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, n := range attempts {
+		m.decodes[name] += n
+	}
+}
@@ -0,0 +1,103 @@
+package flagrep
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Preset is a ready-made search: a regular expression plus the decoder
+// settings that suit it.
+type Preset struct {
+	Name    string
+	Pattern string // regular expression
+	Depth   int    // used unless -depth is given
+	// decoders to use, nil for all of them
+	Decoders []string
+}
+
+// flag bodies are short and never contain whitespace or nested braces
+const flagBody = `\{[^{}\s]{1,200}\}`
+
+// presets builds a Preset from the text after the colon in "-preset name:arg".
+var presets = map[string]func(arg string) (Preset, error){
+	"ctf":     ctfPreset,
+	"secrets": secretsPreset,
+}
+
+// ctfPreset matches common flag formats: flag{...}, CTF{...}, picoCTF{...},
+// HTB{...} and friends, and bare 32 character hex flags. With a prefix, as in
+// "ctf:DUCTF", only flags in that event's format are matched.
+func ctfPreset(prefix string) (Preset, error) {
+	p := Preset{Name: "ctf", Depth: 3}
+	if prefix != "" {
+		p.Name += ":" + prefix
+		p.Pattern = regexp.QuoteMeta(prefix) + flagBody
+		return p, nil
+	}
+	p.Pattern = `(?:(?i:flag|ctf|htb|thm)|[A-Za-z0-9_]{1,16}CTF)` + flagBody + `|\b[0-9a-f]{32}\b`
+	return p, nil
+}
+
+// secretPatterns are credentials with a recognizable shape, by kind.
+var secretPatterns = []struct{ kind, pattern string }{
+	{"AWS access key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"GitHub token", `\bgh[pousr]_[A-Za-z0-9]{36,255}\b|\bgithub_pat_[A-Za-z0-9_]{82}\b`},
+	{"Slack token", `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{"Google API key", `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{"Stripe live key", `\b[rs]k_live_[0-9A-Za-z]{24,}\b`},
+	{"private key", `-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`},
+	{"JSON Web Token", `\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`},
+}
+
+// secretsPreset matches the secretPatterns. With an argument, as in
+// "secrets:aws", only the kinds whose name contains it are matched.
+func secretsPreset(only string) (Preset, error) {
+	p := Preset{Name: "secrets", Depth: 2}
+	var alts []string
+	for _, sp := range secretPatterns {
+		if only == "" || strings.Contains(strings.ToLower(sp.kind), strings.ToLower(only)) {
+			alts = append(alts, sp.pattern)
+		}
+	}
+	if len(alts) == 0 {
+		return Preset{}, fmt.Errorf("no secret kind matches %q", only)
+	}
+	if only != "" {
+		p.Name += ":" + only
+	}
+	p.Pattern = strings.Join(alts, "|")
+	return p, nil
+}
+
+// SecretKind names the kind of credential text is, "" if it is none of the
+// secretPatterns.
+func SecretKind(text string) string {
+	for i, re := range secretKindRegexps() {
+		if re.MatchString(text) {
+			return secretPatterns[i].kind
+		}
+	}
+	return ""
+}
+
+var secretKindRegexps = sync.OnceValue(func() []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(secretPatterns))
+	for i, sp := range secretPatterns {
+		res[i] = regexp.MustCompile(`^(?:` + sp.pattern + `)$`)
+	}
+	return res
+})
+
+// LookupPreset resolves a -preset value such as "ctf" or "ctf:picoCTF".
+func LookupPreset(value string) (Preset, error) {
+	name, arg, _ := strings.Cut(value, ":")
+	build, ok := presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(presets)), ", "))
+	}
+	return build(arg)
+}
@@ -0,0 +1,72 @@
+//go:build ignore
+
+// quadgrams_gen counts the letter quadgrams of English texts and writes
+// quadgrams.bin. The table in the tree was made with Go 1.27 on Debian:
+//
+//	G=$(go env GOROOT)
+//	go run quadgrams_gen.go -min 2 $G/src/testdata/Isaac.Newton-Opticks.txt \
+//		$G/src/compress/testdata/gettysburg.txt $G/doc/go_mem.html \
+//		/usr/share/common-licenses/* > quadgrams.bin
+//
+// Case and everything but letters are ignored, and so are the tags of
+// .html files. Each quadgram seen at least -min times becomes two or more
+// bytes: the uvarint gap from the previous quadgram's index (see
+// quadgramIndex) and the natural logarithm of its count in sixteenths.
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func main() {
+	minCount := flag.Int("min", 2, "drop quadgrams seen fewer times")
+	flag.Parse()
+
+	counts := make(map[int]int)
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		text := string(data)
+		if strings.HasSuffix(path, ".html") {
+			text = htmlTag.ReplaceAllString(text, "")
+		}
+		var letters []int
+		for _, c := range []byte(strings.ToUpper(text)) {
+			if c >= 'A' && c <= 'Z' {
+				letters = append(letters, int(c-'A'))
+			}
+		}
+		for i := 0; i+3 < len(letters); i++ {
+			counts[((letters[i]*26+letters[i+1])*26+letters[i+2])*26+letters[i+3]]++
+		}
+	}
+
+	var indexes []int
+	for index, n := range counts {
+		if n >= *minCount {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.Sort(indexes)
+	var out []byte
+	prev := 0
+	for _, index := range indexes {
+		out = binary.AppendUvarint(out, uint64(index-prev))
+		out = append(out, byte(min(255, math.Round(math.Log(float64(counts[index]))*16))))
+		prev = index
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,166 @@
+package flagrep
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// chunkedPayload is a payload pieced together from chunks spread over the
+// lines of a file.
+type chunkedPayload struct {
+	line   int // of the first chunk
+	chunks int
+	text   string
+}
+
+const (
+	// fewer chunks than this are left to the regular search
+	minChunks = 3
+	// shortest average chunk; shorter varying columns are counters, hours
+	// and the like
+	minChunkLen = 4
+	// lines with more tokens than this are prose, not log records
+	maxChunkTokens = 64
+)
+
+// runs of the Base64, Base32 and hex alphabets, which is what the chunks
+// are; dots, dashes and the rest separate them, so DNS labels, URL
+// parameters and log fields each make a token
+var chunkTokenRe = regexp.MustCompile(`[A-Za-z0-9+/]+=*`)
+
+// chunkRow is a line of a group of lines with the same shape.
+type chunkRow struct {
+	line   int
+	tokens []string
+}
+
+// reassembleChunks finds payloads split into encoded chunks over many
+// lines, as exfiltration leaves them in web, proxy and DNS logs. Lines of
+// the same shape, with the same text between their tokens, are grouped, and
+// the column that varies the most holds the chunks. They are joined in file
+// order, or in the order of a sequence number column when there is one;
+// decoding the joined payload is left to the search.
+func reassembleChunks(text string) []chunkedPayload {
+	groups := make(map[string][]chunkRow)
+	var shapes []string
+	line := 0
+	for l := range strings.Lines(text) {
+		line++
+		locs := chunkTokenRe.FindAllStringIndex(l, maxChunkTokens+1)
+		if len(locs) == 0 || len(locs) > maxChunkTokens {
+			continue
+		}
+		var shape strings.Builder
+		tokens := make([]string, len(locs))
+		prev := 0
+		for i, loc := range locs {
+			shape.WriteString(l[prev:loc[0]])
+			shape.WriteByte(0)
+			tokens[i] = l[loc[0]:loc[1]]
+			prev = loc[1]
+		}
+		shape.WriteString(strings.TrimRight(l[prev:], "\r\n"))
+		key := shape.String()
+		if _, ok := groups[key]; !ok {
+			shapes = append(shapes, key)
+		}
+		groups[key] = append(groups[key], chunkRow{line: line, tokens: tokens})
+	}
+
+	var payloads []chunkedPayload
+	for _, key := range shapes {
+		rows := groups[key]
+		if len(rows) < minChunks {
+			continue
+		}
+		col := chunkColumn(rows)
+		if col < 0 {
+			continue
+		}
+		rows = sequenceOrder(rows, col)
+		var payload strings.Builder
+		for _, r := range rows {
+			payload.WriteString(r.tokens[col])
+		}
+		payloads = append(payloads, chunkedPayload{line: rows[0].line, chunks: len(rows), text: payload.String()})
+	}
+	return payloads
+}
+
+// chunkColumn returns the column of varying, not only numeric tokens with
+// the most text, or -1 if there is none.
+func chunkColumn(rows []chunkRow) int {
+	best, bestLen := -1, 0
+	for col := range rows[0].tokens {
+		total, varies, numeric := 0, false, true
+		for _, r := range rows {
+			token := r.tokens[col]
+			total += len(token)
+			varies = varies || token != rows[0].tokens[col]
+			numeric = numeric && isDigits(token)
+		}
+		if varies && !numeric && total >= minChunkLen*len(rows) && total > bestLen {
+			best, bestLen = col, total
+		}
+	}
+	return best
+}
+
+// sequenceOrder sorts rows by the first numeric column other than chunk
+// whose values count up by one, like the sequence numbers of DNS
+// exfiltration tools, once repeats of a chunk are dropped; resolvers and
+// retries log the same query more than once. Without such a column rows
+// stay in file order.
+func sequenceOrder(rows []chunkRow, chunk int) []chunkRow {
+	for col := range rows[0].tokens {
+		if col == chunk {
+			continue
+		}
+		seen := make(map[int]string)
+		var unique []chunkRow
+		var numbers []int
+		ok := true
+		for _, r := range rows {
+			n, err := strconv.Atoi(r.tokens[col])
+			if err != nil || !isDigits(r.tokens[col]) {
+				ok = false
+				break
+			}
+			if prev, dup := seen[n]; dup {
+				if prev != r.tokens[chunk] {
+					ok = false
+					break
+				}
+				continue
+			}
+			seen[n] = r.tokens[chunk]
+			unique = append(unique, r)
+			numbers = append(numbers, n)
+		}
+		if !ok || len(unique) < minChunks {
+			continue
+		}
+		sorted := slices.Sorted(slices.Values(numbers))
+		if sorted[len(sorted)-1]-sorted[0] != len(sorted)-1 {
+			continue
+		}
+		slices.SortStableFunc(unique, func(a, b chunkRow) int {
+			x, _ := strconv.Atoi(a.tokens[col])
+			y, _ := strconv.Atoi(b.tokens[col])
+			return x - y
+		})
+		return unique
+	}
+	return rows
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,60 @@
+package flagrep
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReassembleChunks(t *testing.T) {
+	payload := base64.StdEncoding.EncodeToString([]byte("flag{split_across_requests}"))
+	var log strings.Builder
+	for i, chunk := range slices.Collect(slices.Chunk([]byte(payload), 8)) {
+		fmt.Fprintf(&log, "2024-05-01T10:00:%02d GET /beacon?d=%s HTTP/1.1 200\n", i, chunk)
+		log.WriteString("2024-05-01T10:00:00 GET /index.html HTTP/1.1 200\n")
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.Reassemble = true
+	searcher.scan(context.Background(), []byte(log.String()), "access.log")
+	if len(out.matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(out.matches))
+	}
+	m := out.matches[0]
+	if m.Text != "flag{" || m.Line != 1 || m.Field != "(5 chunks)" || !slices.Equal(m.Decoders, []string{"base64"}) {
+		t.Errorf("got %+v", m)
+	}
+}
+
+func TestReassembleDNSSequence(t *testing.T) {
+	payload := hex.EncodeToString([]byte("flag{dns_labels}"))
+	// out of order, with a query a resolver logged twice
+	queries := []string{"2", "0", "3", "1", "0"}
+	var log strings.Builder
+	for _, seq := range queries {
+		i := int(seq[0] - '0')
+		fmt.Fprintf(&log, "query: %s.%s.x.evil.example IN A\n", seq, payload[8*i:8*i+8])
+	}
+	log.WriteString("query: www.example.com IN A\n")
+
+	payloads := reassembleChunks(log.String())
+	if len(payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(payloads))
+	}
+	if p := payloads[0]; p.text != payload || p.chunks != 4 || p.line != 2 {
+		t.Errorf("got %+v, want %s in 4 chunks from line 2", p, payload)
+	}
+}
+
+func TestReassembleChunksIgnoresConstantLines(t *testing.T) {
+	log := strings.Repeat("GET /index.html HTTP/1.1 200\n", 10) + "a\nb\nc\n"
+	if payloads := reassembleChunks(log); len(payloads) != 0 {
+		t.Errorf("got %+v", payloads)
+	}
+}
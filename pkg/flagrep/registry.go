@@ -0,0 +1,205 @@
+package flagrep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// registryValue is a value of a registry hive, its data rendered as text.
+type registryValue struct {
+	path string // key path and value name, e.g. Software\...\Run\Updater
+	data string
+}
+
+// registry value types
+const (
+	regSZ       = 1
+	regExpandSZ = 2
+	regDWORD    = 4
+	regDWORDBE  = 5
+	regLink     = 6
+	regMultiSZ  = 7
+	regQWORD    = 11
+)
+
+const (
+	// nk flag: the key name is ASCII, not UTF-16LE
+	regCompressed = 0x20
+	// hive bins start after the base block; cell offsets are relative to it
+	regBinsOffset = 0x1000
+	// data larger than this is split into segments listed by a "db" cell
+	regBigDataSegment = 16344
+	// deeper nesting than Windows allows means a corrupted or hostile hive
+	regMaxDepth = 512
+)
+
+// registryValues returns the values of a Windows registry hive file such as
+// NTUSER.DAT or SOFTWARE, ok is false for anything that is not a hive.
+// Strings are decoded from UTF-16, REG_BINARY and the other types are kept
+// as raw bytes and numbers are left out. Corrupted parts of the hive are
+// skipped rather than failing the whole file.
+func registryValues(content []byte) (values []registryValue, ok bool) {
+	if len(content) < regBinsOffset || !bytes.HasPrefix(content, []byte("regf")) {
+		return nil, false
+	}
+	h := &hive{content: content, visited: make(map[uint32]bool)}
+	h.walkKey(binary.LittleEndian.Uint32(content[0x24:]), "", 0)
+	return h.values, true
+}
+
+type hive struct {
+	content []byte
+	visited map[uint32]bool
+	values  []registryValue
+}
+
+// cell returns the data of the cell at off, nil if it is out of bounds.
+func (h *hive) cell(off uint32) []byte {
+	pos := regBinsOffset + int64(off)
+	if pos+4 > int64(len(h.content)) {
+		return nil
+	}
+	// negative sizes mark allocated cells
+	size := int64(int32(binary.LittleEndian.Uint32(h.content[pos:])))
+	size = max(size, -size)
+	if size < 4 || pos+size > int64(len(h.content)) {
+		return nil
+	}
+	return h.content[pos+4 : pos+size]
+}
+
+func (h *hive) walkKey(off uint32, path string, depth int) {
+	nk := h.cell(off)
+	if depth > regMaxDepth || h.visited[off] || len(nk) < 0x4c || string(nk[:2]) != "nk" {
+		return
+	}
+	h.visited[off] = true
+
+	if depth > 0 {
+		// leave out the root key, whose name depends on the tool that saved the hive
+		nameLen := int(binary.LittleEndian.Uint16(nk[0x48:]))
+		if 0x4c+nameLen > len(nk) {
+			return
+		}
+		name := regName(nk[0x4c:0x4c+nameLen], binary.LittleEndian.Uint16(nk[2:])&regCompressed != 0)
+		path = strings.TrimPrefix(path+`\`+name, `\`)
+	}
+
+	values := h.cell(binary.LittleEndian.Uint32(nk[0x28:]))
+	count := int(binary.LittleEndian.Uint32(nk[0x24:]))
+	for i := 0; i < count && 4*i+4 <= len(values); i++ {
+		h.readValue(binary.LittleEndian.Uint32(values[4*i:]), path)
+	}
+
+	if binary.LittleEndian.Uint32(nk[0x14:]) > 0 {
+		h.walkSubkeys(binary.LittleEndian.Uint32(nk[0x1c:]), path, depth+1, 0)
+	}
+}
+
+// walkSubkeys follows a subkey list: lf and lh carry a hash next to every
+// offset, li does not, and ri points to further lists.
+func (h *hive) walkSubkeys(off uint32, path string, depth, indirection int) {
+	list := h.cell(off)
+	if len(list) < 4 || indirection > 1 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint16(list[2:]))
+	stride := 4
+	switch string(list[:2]) {
+	case "lf", "lh":
+		stride = 8
+	case "li", "ri":
+	default:
+		return
+	}
+	for i := 0; i < count && 4+stride*i+4 <= len(list); i++ {
+		child := binary.LittleEndian.Uint32(list[4+stride*i:])
+		if string(list[:2]) == "ri" {
+			h.walkSubkeys(child, path, depth, indirection+1)
+		} else {
+			h.walkKey(child, path, depth)
+		}
+	}
+}
+
+func (h *hive) readValue(off uint32, path string) {
+	vk := h.cell(off)
+	if len(vk) < 0x14 || string(vk[:2]) != "vk" {
+		return
+	}
+	nameLen := int(binary.LittleEndian.Uint16(vk[2:]))
+	if 0x14+nameLen > len(vk) {
+		return
+	}
+	name := regName(vk[0x14:0x14+nameLen], binary.LittleEndian.Uint16(vk[0x10:])&1 != 0)
+	if name == "" {
+		name = "(Default)"
+	}
+
+	typ := binary.LittleEndian.Uint32(vk[0x0c:])
+	if typ == regDWORD || typ == regDWORDBE || typ == regQWORD {
+		return
+	}
+	data := h.valueData(vk)
+	if len(data) == 0 {
+		return
+	}
+
+	var text string
+	switch typ {
+	case regSZ, regExpandSZ, regLink:
+		text = strings.TrimRight(decodeUTF16LE(data), "\x00")
+	case regMultiSZ:
+		text = strings.Join(strings.FieldsFunc(decodeUTF16LE(data), func(r rune) bool { return r == 0 }), "\n")
+	default:
+		text = string(data)
+	}
+	h.values = append(h.values, registryValue{path: strings.TrimPrefix(path+`\`+name, `\`), data: text})
+}
+
+// valueData returns the data of a vk cell: stored in the cell itself when
+// the high bit of the size is set, in a data cell, or in big data segments.
+func (h *hive) valueData(vk []byte) []byte {
+	size := binary.LittleEndian.Uint32(vk[4:])
+	if size&0x80000000 != 0 {
+		return vk[8 : 8+min(size&0x7fffffff, 4)]
+	}
+	cell := h.cell(binary.LittleEndian.Uint32(vk[8:]))
+	if size > regBigDataSegment && len(cell) >= 8 && string(cell[:2]) == "db" {
+		segments := h.cell(binary.LittleEndian.Uint32(cell[4:]))
+		var data []byte
+		count := int(binary.LittleEndian.Uint16(cell[2:]))
+		for i := 0; i < count && 4*i+4 <= len(segments) && uint32(len(data)) < size; i++ {
+			segment := h.cell(binary.LittleEndian.Uint32(segments[4*i:]))
+			data = append(data, segment[:min(len(segment), regBigDataSegment, int(size)-len(data))]...)
+		}
+		return data
+	}
+	if uint32(len(cell)) < size {
+		return nil
+	}
+	return cell[:size]
+}
+
+// regName decodes a key or value name, ASCII (really Latin-1) when
+// compressed and UTF-16LE otherwise.
+func regName(b []byte, compressed bool) string {
+	if !compressed {
+		return decodeUTF16LE(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func decodeUTF16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
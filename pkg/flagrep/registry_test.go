@@ -0,0 +1,170 @@
+package flagrep
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"maps"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// hiveBuilder lays out cells for a minimal registry hive.
+type hiveBuilder struct {
+	bins []byte
+}
+
+func (b *hiveBuilder) cell(data []byte) uint32 {
+	off := uint32(len(b.bins))
+	size := (4 + len(data) + 7) &^ 7
+	c := make([]byte, size)
+	binary.LittleEndian.PutUint32(c, uint32(-int32(size)))
+	copy(c[4:], data)
+	b.bins = append(b.bins, c...)
+	return off
+}
+
+func (b *hiveBuilder) offsets(offs ...uint32) uint32 {
+	data := make([]byte, 4*len(offs))
+	for i, off := range offs {
+		binary.LittleEndian.PutUint32(data[4*i:], off)
+	}
+	return b.cell(data)
+}
+
+func (b *hiveBuilder) key(name string, subkeys, values []uint32) uint32 {
+	nk := make([]byte, 0x4c+len(name))
+	copy(nk, "nk")
+	binary.LittleEndian.PutUint16(nk[2:], regCompressed)
+	binary.LittleEndian.PutUint32(nk[0x1c:], 0xffffffff)
+	binary.LittleEndian.PutUint32(nk[0x28:], 0xffffffff)
+	if len(subkeys) > 0 {
+		lf := make([]byte, 4+8*len(subkeys))
+		copy(lf, "lf")
+		binary.LittleEndian.PutUint16(lf[2:], uint16(len(subkeys)))
+		for i, off := range subkeys {
+			binary.LittleEndian.PutUint32(lf[4+8*i:], off)
+		}
+		binary.LittleEndian.PutUint32(nk[0x14:], uint32(len(subkeys)))
+		binary.LittleEndian.PutUint32(nk[0x1c:], b.cell(lf))
+	}
+	if len(values) > 0 {
+		binary.LittleEndian.PutUint32(nk[0x24:], uint32(len(values)))
+		binary.LittleEndian.PutUint32(nk[0x28:], b.offsets(values...))
+	}
+	binary.LittleEndian.PutUint16(nk[0x48:], uint16(len(name)))
+	copy(nk[0x4c:], name)
+	return b.cell(nk)
+}
+
+func (b *hiveBuilder) value(name string, typ uint32, data []byte) uint32 {
+	vk := make([]byte, 0x14+len(name))
+	copy(vk, "vk")
+	binary.LittleEndian.PutUint16(vk[2:], uint16(len(name)))
+	binary.LittleEndian.PutUint32(vk[4:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(vk[0x0c:], typ)
+	binary.LittleEndian.PutUint16(vk[0x10:], 1)
+	copy(vk[0x14:], name)
+	switch {
+	case len(data) <= 4:
+		binary.LittleEndian.PutUint32(vk[4:], uint32(len(data))|0x80000000)
+		copy(vk[8:], data)
+	case len(data) > regBigDataSegment:
+		var segments []uint32
+		for chunk := range slices.Chunk(data, regBigDataSegment) {
+			segments = append(segments, b.cell(chunk))
+		}
+		db := make([]byte, 8)
+		copy(db, "db")
+		binary.LittleEndian.PutUint16(db[2:], uint16(len(segments)))
+		binary.LittleEndian.PutUint32(db[4:], b.offsets(segments...))
+		binary.LittleEndian.PutUint32(vk[8:], b.cell(db))
+	default:
+		binary.LittleEndian.PutUint32(vk[8:], b.cell(data))
+	}
+	return b.cell(vk)
+}
+
+func (b *hiveBuilder) bytes(root uint32) []byte {
+	base := make([]byte, regBinsOffset)
+	copy(base, "regf")
+	binary.LittleEndian.PutUint32(base[0x24:], root)
+	return append(base, b.bins...)
+}
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestRegistryValues(t *testing.T) {
+	var b hiveBuilder
+	payload := base64.StdEncoding.EncodeToString([]byte("powershell -c flag{persist}"))
+	big := bytes.Repeat([]byte{0}, 20000)
+	copy(big[19000:], "flag{big}")
+	run := b.key("Run", nil, []uint32{
+		b.value("Updater", regSZ, utf16le(payload)),
+		b.value("Count", regDWORD, []byte{1, 0, 0, 0}),
+		b.value("", regMultiSZ, append(utf16le("a"), utf16le("b")...)),
+	})
+	software := b.key("Software", []uint32{run}, []uint32{b.value("Blob", 3, big)})
+	hive := b.bytes(b.key("ROOT", []uint32{software}, nil))
+
+	values, ok := registryValues(hive)
+	if !ok {
+		t.Fatal("not recognized as a hive")
+	}
+	got := make(map[string]string)
+	for _, v := range values {
+		got[v.path] = v.data
+	}
+	want := map[string]string{
+		`Software\Blob`:          string(big),
+		`Software\Run\Updater`:   payload,
+		`Software\Run\(Default)`: "a\nb",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got values %v", slices.Collect(maps.Keys(got)))
+	}
+	for path, data := range want {
+		if got[path] != data {
+			t.Errorf("%s = %.40q, want %.40q", path, got[path], data)
+		}
+	}
+
+	searcher := NewSearcher(nil, "flag{", false, true, 1, 1, 0, 0, false)
+	out := &collectOutput{}
+	searcher.Output = out
+	searcher.scan(context.Background(), hive, "NTUSER.DAT")
+	fields := make(map[string]bool)
+	for _, m := range out.matches {
+		fields[m.Field] = true
+	}
+	if !fields[`Software\Run\Updater`] || !fields[`Software\Blob`] {
+		t.Errorf("matches in %v, want the Updater and Blob values", fields)
+	}
+
+	// truncated and looping hives must not crash or hang
+	for n := regBinsOffset; n < len(hive); n += 97 {
+		registryValues(hive[:n])
+	}
+	var loop hiveBuilder
+	lf := make([]byte, 12)
+	copy(lf, "lf")
+	binary.LittleEndian.PutUint16(lf[2:], 1)
+	binary.LittleEndian.PutUint32(lf[4:], 0)
+	// the root key lists itself as its subkey
+	loop.key("ROOT", nil, nil)
+	list := loop.cell(lf)
+	nk := loop.bins[4:]
+	binary.LittleEndian.PutUint32(nk[0x14:], 1)
+	binary.LittleEndian.PutUint32(nk[0x1c:], list)
+	if _, ok := registryValues(loop.bytes(0)); !ok {
+		t.Error("looping hive not recognized")
+	}
+}
@@ -0,0 +1,109 @@
+package flagrep
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Options configures Scan. The zero value of a field selects the same
+// default as the flagrep command, except that matches carry no context.
+type Options struct {
+	// files and directories to search, stdin when empty
+	Paths []string
+	// literal to search for, or the regular expression with Regexp
+	Pattern       string
+	Regexp        bool
+	Recursive     bool
+	CaseSensitive bool
+	// files searched in parallel, scaled automatically when 0
+	Workers int
+	// decoder combination depth, 2 when 0
+	Depth         int
+	ContextBefore int
+	ContextAfter  int
+	// names of the decoders to try, all of Decoders() when nil
+	Decoders      []string
+	MinConfidence float64
+	Certificates  bool
+	StackStrings  bool
+	Reassemble    bool
+	DNS           bool
+	Explain       bool
+	// fields to search in JSON Lines input, whole files when nil
+	JSONFields []string
+	// diagnostics, discarded when nil
+	Logger *slog.Logger
+}
+
+// Scan starts searching in the background and returns the matches as they
+// are found. The channel is closed when the scan is done or ctx is
+// cancelled; the caller must keep receiving until then. wait then returns
+// what stopped the scan short, as Searcher.RunContext does: ctx.Err(), or
+// the error reading stdin.
+func Scan(ctx context.Context, opts Options) (matches <-chan Match, wait func() error, err error) {
+	if opts.Pattern == "" {
+		return nil, nil, errors.New("flagrep: empty pattern")
+	}
+	depth := opts.Depth
+	if depth <= 0 {
+		depth = 2
+	}
+
+	s := NewSearcher(opts.Paths, opts.Pattern, opts.Recursive, opts.CaseSensitive, opts.Workers, depth, opts.ContextBefore, opts.ContextAfter, false)
+	if opts.Regexp {
+		if err := s.UseRegexp(opts.Pattern); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.Decoders != nil {
+		all := Decoders()
+		s.Decoders = make(map[string]DecoderFunc)
+		for _, name := range opts.Decoders {
+			d, ok := all[name]
+			if !ok {
+				return nil, nil, errors.New("flagrep: unknown decoder " + name)
+			}
+			s.Decoders[name] = d
+		}
+	}
+	s.Logger = opts.Logger
+	if s.Logger == nil {
+		s.Logger = slog.New(slog.DiscardHandler)
+	}
+	s.MinConfidence = opts.MinConfidence
+	s.Certificates = opts.Certificates
+	s.StackStrings = opts.StackStrings
+	s.Reassemble = opts.Reassemble
+	s.DNS = opts.DNS
+	s.Explain = opts.Explain
+	s.JSONFields = opts.JSONFields
+
+	found := make(chan Match)
+	s.Output = chanOutput{ctx: ctx, matches: found}
+	done := make(chan struct{})
+	var runErr error
+	go func() {
+		defer close(found)
+		defer close(done)
+		runErr = s.RunContext(ctx)
+	}()
+	return found, func() error {
+		<-done
+		return runErr
+	}, nil
+}
+
+// chanOutput sends the matches of a Scan until its context is done.
+type chanOutput struct {
+	discardOutput
+	ctx     context.Context
+	matches chan<- Match
+}
+
+func (o chanOutput) Match(m Match) {
+	select {
+	case o.matches <- m:
+	case <-o.ctx.Done():
+	}
+}
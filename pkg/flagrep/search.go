@@ -0,0 +1,1011 @@
+package flagrep
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Causes of a scan stopping early, see Searcher.MaxResults and MaxTime.
+var (
+	ErrMaxResults = errors.New("maximum number of results reached")
+	ErrMaxTime    = errors.New("maximum scan time reached")
+)
+
+// LevelTrace is below slog.LevelDebug, for every decoded state.
+const LevelTrace = slog.LevelDebug - 4
+
+// Searcher looks for a pattern in files and in everything the decoders can
+// make of them, breadth-first up to Depth decoders deep.
+type Searcher struct {
+	Paths         []string
+	Pattern       string
+	Recursive     bool
+	CaseSensitive bool
+	Concurrency   int
+	Depth         int
+	// the expensive solvers of -enable-solver only run on this many levels
+	BruteDepth int
+	Verbose    bool
+	Logger     *slog.Logger
+	Decoders   map[string]DecoderFunc
+	// overrides Pattern, see UseRegexp; nil while the literal Pattern is
+	// matched without the regexp engine
+	Regexp *regexp.Regexp
+	// the expression given to UseRegexp
+	expr string
+	// name of the preset Pattern names, whose expression is searched
+	Preset        string
+	ContextBefore int
+	ContextAfter  int
+	// matches scoring below this are dropped, see confidence()
+	MinConfidence float64
+	// matches already reported by a previous scan are not reported again
+	Baseline Baseline
+	// known-good matches, see IgnoreList
+	Ignore *IgnoreList
+	// files with a known-good hash are skipped
+	HashList *HashList
+	// when set, symbolic links met in a walk that lead out of this
+	// directory are skipped, see InsideRoot
+	Root string
+	// runs YARA rules through an external engine, nil to disable
+	Yara *YaraRunner
+	// unpacks UPX-packed executables to search them as well, nil to disable
+	UPX *UPXRunner
+	// report certificates and private keys found in any decoded state
+	Certificates bool
+	// also search strings that executables build on the stack
+	StackStrings bool
+	// also search payloads split into chunks over many lines, see
+	// reassembleChunks
+	Reassemble bool
+	// also search what the queries to each domain in DNS logs carry in
+	// their subdomain labels, see dnsPayloads
+	DNS bool
+	// record how every match was decoded, see explainSteps
+	Explain bool
+	// charset of the files, a key of Charsets, or "" to detect it per file
+	Charset string
+	// search only these fields of the JSON object on every line, see
+	// scanJSONLines
+	JSONFields []string
+	// stop the scan after this many matches or this long, and run the
+	// -enable-solver decoders at most MaxSolverRuns times; 0 for no limit
+	MaxResults    int
+	MaxTime       time.Duration
+	MaxSolverRuns int
+	// counts work across scans, nil to disable
+	Metrics *Metrics
+	// orders decoders by how often they led to matches, nil to disable
+	Stats  *DecoderStats
+	Output Output
+
+	literal      *literalMatcher
+	stop         context.CancelCauseFunc
+	filesScanned atomic.Int64
+	matchCount   atomic.Int64
+	solverRuns   atomic.Int64
+}
+
+func NewSearcher(paths []string, pattern string, recursive, caseSensitive bool, concurrency, depth, contextBefore, contextAfter int, verbose bool) *Searcher {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelInfo
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	literal := newLiteralMatcher(pattern, caseSensitive)
+	var re *regexp.Regexp
+	switch {
+	case literal != nil:
+		// no regexp needed
+	case caseSensitive:
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	default:
+		re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+	}
+
+	return &Searcher{
+		Paths:         paths,
+		Pattern:       pattern,
+		Recursive:     recursive,
+		CaseSensitive: caseSensitive,
+		Concurrency:   concurrency,
+		Depth:         depth,
+		BruteDepth:    1,
+		MaxSolverRuns: 100,
+		ContextBefore: contextBefore,
+		ContextAfter:  contextAfter,
+		Verbose:       verbose,
+		Logger:        logger,
+		Decoders:      Decoders(),
+		Regexp:        re,
+		Output:        discardOutput{},
+		literal:       literal,
+	}
+}
+
+// UseRegexp makes the searcher match the regular expression expr instead of
+// the literal pattern, honoring CaseSensitive.
+func (s *Searcher) UseRegexp(expr string) error {
+	compiled := expr
+	if !s.CaseSensitive {
+		compiled = "(?i)" + expr
+	}
+	re, err := regexp.Compile(compiled)
+	if err != nil {
+		return err
+	}
+	s.Regexp = re
+	s.expr = expr
+	return nil
+}
+
+// expression returns what is searched: the regular expression when there is
+// one, else the literal Pattern.
+func (s *Searcher) expression() string {
+	switch {
+	case s.expr != "":
+		return s.expr
+	case s.Regexp != nil:
+		return s.Regexp.String()
+	}
+	return s.Pattern
+}
+
+// Run searches every path, or stdin when there are none, reporting to
+// Output.
+func (s *Searcher) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext is Run, stopping early once ctx is done. Matches found until
+// then are reported and ctx.Err() is returned. Reaching MaxResults or
+// MaxTime also stops the scan, but is not an error; ScanSummary.StoppedBy
+// tells it apart from a complete scan.
+func (s *Searcher) RunContext(ctx context.Context) (err error) {
+	started := time.Now()
+	s.Output.Begin(ScanInfo{
+		Pattern:       s.expression(),
+		Preset:        s.Preset,
+		Paths:         s.Paths,
+		Recursive:     s.Recursive,
+		CaseSensitive: s.CaseSensitive,
+		Workers:       s.Concurrency,
+		Depth:         s.Depth,
+		ContextBefore: s.ContextBefore,
+		ContextAfter:  s.ContextAfter,
+		Started:       started,
+	})
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	if s.MaxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, s.MaxTime, ErrMaxTime)
+		defer cancel()
+	}
+	s.stop = stop
+
+	var workers []WorkerStats
+	defer func() {
+		stoppedBy := ""
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, ErrMaxResults):
+			stoppedBy = "max-results"
+		case errors.Is(cause, ErrMaxTime):
+			stoppedBy = "max-time"
+		}
+		if stoppedBy != "" && err == ctx.Err() {
+			err = nil
+		}
+		s.Output.End(ScanSummary{
+			FilesScanned: s.filesScanned.Load(),
+			Matches:      s.matchCount.Load(),
+			Duration:     time.Since(started),
+			Workers:      workers,
+			StoppedBy:    stoppedBy,
+		})
+	}()
+
+	// if no paths provided, read from stdin
+	if len(s.Paths) == 0 {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		s.scan(ctx, content, "(stdin)")
+		return ctx.Err()
+	}
+
+	// walk the directories and send files to the workers
+	pool := s.newWorkerPool(ctx)
+	for _, path := range s.Paths {
+		if ctx.Err() != nil {
+			break
+		}
+		if path == "-" {
+			content, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				s.Logger.Error("reading stdin", "err", err)
+				continue
+			}
+			s.scan(ctx, content, "(stdin)")
+			continue
+		}
+
+		err := s.walk(ctx, path, pool.submit)
+		if err != nil && ctx.Err() == nil {
+			s.Logger.Error("walking path", "path", path, "err", err)
+		}
+	}
+
+	workers = pool.wait()
+
+	return ctx.Err()
+}
+
+func (s *Searcher) walk(ctx context.Context, root string, submit func(path string)) error {
+	return s.walkFiles(ctx, root, submit, func(path, reason string) {
+		s.Logger.Info("skipping path", "path", path, "reason", reason)
+	})
+}
+
+// walkFiles calls visit for every file under root that is to be read and
+// skip for every path left out, with the reason, until ctx is done.
+// Devices, pipes and mostly sparse files are skipped, root included, so
+// nothing opens them; pipe output to "-" instead. On Windows the alternate
+// data streams of files and directories are visited as well, as
+// "path:stream".
+func (s *Searcher) walkFiles(ctx context.Context, root string, visit func(path string), skip func(path, reason string)) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if reason := specialFileReason(root, info); reason != "" {
+			skip(root, reason)
+			return nil
+		}
+		visit(root)
+		visitStreams(root, visit)
+		return nil
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			skip(path, err.Error())
+			return nil
+		}
+		if !info.IsDir() {
+			if reason := specialFileReason(path, info); reason != "" {
+				skip(path, reason)
+				return nil
+			}
+			if s.Root != "" && info.Mode()&os.ModeSymlink != 0 && !InsideRoot(s.Root, path) {
+				skip(path, "symbolic link out of "+s.Root)
+				return nil
+			}
+			visit(path)
+		} else if !s.Recursive && path != root {
+			skip(path, "directory, not recursing without -r")
+			return filepath.SkipDir
+		}
+		visitStreams(path, visit)
+		return nil
+	})
+}
+
+func visitStreams(path string, visit func(path string)) {
+	for _, stream := range alternateStreams(path) {
+		visit(path + ":" + stream)
+	}
+}
+
+// ListFiles writes the files a scan would read as "scan PATH" lines and the
+// ones it would leave out as "skip PATH: REASON", without searching them.
+func (s *Searcher) ListFiles(w io.Writer) error {
+	skip := func(path, reason string) { fmt.Fprintf(w, "skip %s: %s\n", path, reason) }
+	visit := func(path string) {
+		if reason := s.skipReason(path); reason != "" {
+			skip(path, reason)
+			return
+		}
+		fmt.Fprintf(w, "scan %s\n", path)
+	}
+
+	if len(s.Paths) == 0 {
+		fmt.Fprintln(w, "scan (stdin)")
+		return nil
+	}
+	for _, path := range s.Paths {
+		if path == "-" {
+			fmt.Fprintln(w, "scan (stdin)")
+			continue
+		}
+		if err := s.walkFiles(context.Background(), path, visit, skip); err != nil {
+			skip(path, err.Error())
+		}
+	}
+	return nil
+}
+
+// skipReason tells why processFile would not search path, "" if it would.
+func (s *Searcher) skipReason(path string) string {
+	if s.HashList == nil {
+		// cheaper than reading the whole file
+		f, err := os.Open(path)
+		if err != nil {
+			return err.Error()
+		}
+		f.Close()
+		return ""
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	if s.HashList.Known(content) {
+		return "known file in -hashlist"
+	}
+	return ""
+}
+
+// processFile searches the file at path and returns its size, or -1 if it
+// was skipped.
+func (s *Searcher) processFile(ctx context.Context, path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		s.Logger.Info("skipping file", "path", path, "err", err)
+		return -1
+	}
+
+	if s.HashList.Known(content) {
+		s.Logger.Info("skipping known file", "path", path)
+		return -1
+	}
+
+	s.scan(ctx, content, path)
+	if s.Yara != nil {
+		s.scanYara(content, path)
+	}
+	if s.UPX != nil && upxPacked(content) {
+		if unpacked, err := s.UPX.Unpack(content); err != nil {
+			s.Logger.Warn("unpacking upx", "path", path, "err", err)
+		} else {
+			s.Logger.Info("unpacked upx", "path", path, "size", len(unpacked))
+			s.searchBFS(ctx, string(unpacked), path+" (upx unpacked)")
+		}
+	}
+	return len(content)
+}
+
+// searchBytes searches the bytes of a file. The overlay of a PE file is
+// searched first and on its own, so that decoders see the payload hidden
+// there rather than the whole executable; the rest of the file follows,
+// with the overlay blanked so that offsets stay those in the file.
+func (s *Searcher) searchBytes(ctx context.Context, content []byte, path string) {
+	f := openPE(content)
+	if f == nil {
+		s.searchBFS(ctx, string(content), path)
+		return
+	}
+	start, end := peOverlay(f, content)
+	if start == end {
+		s.searchBFS(ctx, string(content), path)
+		return
+	}
+	s.Logger.Info("pe overlay", "path", path, "offset", start, "size", end-start)
+	fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+	s.searchFrom(ctx, string(content[start:end]), origin{path: path, field: "overlay", fileHash: fileHash})
+	s.searchFrom(ctx, string(content[:start])+strings.Repeat("\x00", end-start)+string(content[end:]), origin{path: path, fileHash: fileHash})
+}
+
+// scanYara reports the string matches of the external YARA engine, with
+// context taken from the file like any other match.
+func (s *Searcher) scanYara(content []byte, path string) {
+	matches, err := s.Yara.Scan(path)
+	if err != nil {
+		s.Logger.Warn("running yara", "path", path, "err", err)
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	fileHash := hex.EncodeToString(sum[:])
+	for _, ym := range matches {
+		m := Match{
+			File:     path,
+			FileHash: fileHash,
+			Decoders: []string{},
+			Pattern:  ym.StringID,
+			Rule:     ym.Rule,
+			Text:     ym.Data,
+			Offset:   ym.Offset,
+		}
+		// condition-only rules have no string to show context for
+		if end := ym.Offset + ym.Length; ym.StringID != "" && end <= len(content) {
+			m.Text = string(content[ym.Offset:end])
+			m.Before = string(content[max(ym.Offset-s.ContextBefore, 0):ym.Offset])
+			m.After = string(content[end:min(end+s.ContextAfter, len(content))])
+			m.Line = bytes.Count(content[:ym.Offset], []byte("\n")) + 1
+		}
+		s.emit(m)
+	}
+}
+
+// ScanBytes searches content as if it had been read from a file called
+// name, reporting matches to Output without the Begin and End of a Run.
+func (s *Searcher) ScanBytes(ctx context.Context, content []byte, name string) {
+	s.scan(ctx, content, name)
+}
+
+// scan searches a file's content, the overlay of a PE file first, plus the
+// build information when the file is a Go binary, since module paths,
+// versions and -ldflags values are not stored as contiguous strings; the string literals of a .NET assembly,
+// which are UTF-16 in its #US heap; the string tables and native libraries
+// compressed inside an APK; and every value of a registry hive or a Windows
+// event log, whose strings are UTF-16 and whose key paths and event IDs tell
+// where a payload came from. HTTP Archives are searched by request and
+// response instead of as JSON. With Reassemble and DNS, payloads split over
+// the lines of a log or the queries to a domain are pieced together and
+// searched as well.
+func (s *Searcher) scan(ctx context.Context, content []byte, path string) {
+	s.filesScanned.Add(1)
+	s.Metrics.addFile(len(content))
+	text, charset := s.transcode(content)
+	if parts, ok := harParts(content); ok {
+		s.Logger.Info("http archive", "path", path, "parts", len(parts))
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, p := range parts {
+			if ctx.Err() != nil {
+				break
+			}
+			s.searchFrom(ctx, p.text, origin{path: path, record: p.entry, field: p.field, fileHash: fileHash})
+		}
+	} else if len(s.JSONFields) > 0 {
+		if charset != "utf8" {
+			s.Logger.Debug("transcoded", "path", path, "charset", charset)
+			s.scanJSONLines(ctx, []byte(text), path)
+		} else {
+			s.scanJSONLines(ctx, content, path)
+		}
+	} else {
+		// the bytes as they are, in case the guess is wrong, and then
+		// the text
+		s.searchBytes(ctx, content, path)
+		if charset != "utf8" {
+			s.Logger.Debug("transcoded", "path", path, "charset", charset)
+			fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+			s.searchFrom(ctx, text, origin{path: path, fileHash: fileHash, nonASCII: eightBitCharsets[charset]})
+		}
+	}
+
+	if (s.Reassemble || s.DNS) && charset == "utf8" {
+		text = string(content)
+	}
+	if s.Reassemble {
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, p := range reassembleChunks(text) {
+			if ctx.Err() != nil {
+				break
+			}
+			s.Logger.Debug("reassembled", "path", path, "line", p.line, "chunks", p.chunks)
+			field := "(" + strconv.Itoa(p.chunks) + " chunks)"
+			s.searchFrom(ctx, p.text, origin{path: path, line: p.line, field: field, fileHash: fileHash})
+		}
+	}
+	if s.DNS {
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, p := range dnsPayloads(text) {
+			if ctx.Err() != nil {
+				break
+			}
+			// undecoded labels are left to the decoders
+			payload, field := p.labels, "(DNS "+p.domain+")"
+			if p.encoding != "" {
+				payload, field = p.decoded, "(DNS "+p.domain+" "+p.encoding+")"
+			}
+			s.Logger.Info("dns payload", "path", path, "domain", p.domain, "queries", p.queries,
+				"encoding", p.encoding, "payload", payload[:min(len(payload), maxLoggedPayload)])
+			s.searchFrom(ctx, payload, origin{path: path, line: p.line, field: field, fileHash: fileHash})
+		}
+	}
+
+	if info, ok := goBuildInfo(content); ok {
+		s.Logger.Info("go binary", "path", path, "module", info.Path, "go", info.GoVersion)
+		s.searchBFS(ctx, info.String(), path+" (go buildinfo)")
+	}
+
+	if literals := dotNetUserStrings(content); len(literals) > 0 {
+		s.Logger.Info(".net assembly", "path", path, "strings", len(literals))
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		s.searchFrom(ctx, strings.Join(literals, "\n"), origin{path: path, field: "#US", fileHash: fileHash})
+	}
+
+	if values, ok := registryValues(content); ok {
+		s.Logger.Info("registry hive", "path", path, "values", len(values))
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, v := range values {
+			if ctx.Err() != nil {
+				break
+			}
+			s.searchFrom(ctx, v.data, origin{path: path, field: v.path, fileHash: fileHash})
+		}
+	}
+
+	if components, ok := apkComponents(content); ok {
+		s.Logger.Info("android package", "path", path, "components", len(components))
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, c := range components {
+			for _, text := range c.texts {
+				if ctx.Err() != nil {
+					break
+				}
+				s.searchFrom(ctx, text, origin{path: path, field: c.path, fileHash: fileHash})
+			}
+		}
+	}
+
+	if values, ok := evtxValues(content); ok {
+		s.Logger.Info("event log", "path", path, "values", len(values))
+		fileHash := sync.OnceValue(func() string { return hashString(string(content)) })
+		for _, v := range values {
+			if ctx.Err() != nil {
+				break
+			}
+			field := v.name
+			if v.eventID >= 0 {
+				field = strings.TrimSpace("EventID " + strconv.Itoa(v.eventID) + " " + v.name)
+			}
+			s.searchFrom(ctx, v.text, origin{path: path, record: v.record, field: field, fileHash: fileHash})
+		}
+	}
+
+	if s.StackStrings {
+		if stack := stackStringsContent(content); stack != "" {
+			s.searchBFS(ctx, stack, path+" (stack strings)")
+		}
+	}
+}
+
+type searchState struct {
+	content         string
+	appliedDecoders []string
+	// other chains of the same length that produced identical content
+	alternatives [][]string
+	depth        int
+	parent       *searchState
+}
+
+// transcode returns content as UTF-8 text and the charset it was in, "utf8"
+// when it is searched as it is.
+func (s *Searcher) transcode(content []byte) (string, string) {
+	charset := s.Charset
+	if charset == "" {
+		charset = detectCharset(content)
+	}
+	decode, ok := Charsets[charset]
+	if !ok || charset == "utf8" {
+		return "", "utf8"
+	}
+	return strings.TrimPrefix(decode(content), "\ufeff"), charset
+}
+
+// origin tells where the content given to searchFrom came from.
+type origin struct {
+	path string
+	// the line in the file when the content is all on one, like a JSON record
+	line int
+	// the JSON record or event log record and the path of the field in it;
+	// in registry hives, the path of the value
+	record int
+	field  string
+	// hashes the whole file when the content is only part of it
+	fileHash func() string
+	// report only matches with non-ASCII text; for text transcoded from
+	// an 8-bit charset, the others are in the raw bytes as well
+	nonASCII bool
+}
+
+func (o origin) hash(content string) string {
+	if o.fileHash != nil {
+		return o.fileHash()
+	}
+	return hashString(content)
+}
+
+func (s *Searcher) searchBFS(ctx context.Context, initialContent, path string) {
+	s.searchFrom(ctx, initialContent, origin{path: path})
+}
+
+func (s *Searcher) searchFrom(ctx context.Context, initialContent string, o origin) {
+	path := o.path
+	// hashed lazily, most files never match
+	fileHash := ""
+
+	// map iteration order is random, sort so results are reproducible
+	names := slices.Sorted(maps.Keys(s.Decoders))
+	var counts map[string]decoderCount
+	var fruitless map[string]bool
+	if s.Stats != nil {
+		names = s.Stats.order(names)
+		fruitless = s.Stats.fruitless()
+		counts = make(map[string]decoderCount, len(names))
+		defer s.Stats.add(counts)
+	}
+
+	root := &searchState{
+		content:         initialContent,
+		appliedDecoders: []string{},
+		depth:           0,
+	}
+	queue := []*searchState{root}
+	// every content we have produced so far; reaching the same content via
+	// another chain would only repeat the same matches and subtree
+	seen := map[string]*searchState{initialContent: root}
+	reportedCerts := make(map[string]bool)
+	solverRuns := 0
+	found := newMatchSet()
+	defer found.flush(s.emit, s.Output.Truncated)
+	matched := false
+	trace := s.Logger.Enabled(ctx, LevelTrace)
+	var attempts map[string]int64
+	if s.Metrics != nil {
+		attempts = make(map[string]int64, len(names))
+		defer s.Metrics.addDecodes(attempts)
+	}
+
+	for len(queue) > 0 && ctx.Err() == nil {
+		currentState := queue[0]
+		queue = queue[1:]
+		if s.matches(currentState.content) {
+			//found match
+			matched = true
+			if counts != nil {
+				countHits(counts, currentState)
+			}
+			if fileHash == "" {
+				fileHash = o.hash(initialContent)
+			}
+			s.reportMatches(o, fileHash, currentState, found)
+		}
+		if s.Certificates {
+			for _, f := range findCertificates(currentState.content) {
+				// the same PEM block survives decoders like space_removal
+				key := f.rule + "\x00" + f.detail
+				if reportedCerts[key] {
+					continue
+				}
+				reportedCerts[key] = true
+				if fileHash == "" {
+					fileHash = o.hash(initialContent)
+				}
+				end := f.offset
+				if strings.HasPrefix(currentState.content[f.offset:], f.text) {
+					end += len(f.text)
+				}
+				s.emit(Match{
+					File:         path,
+					FileHash:     fileHash,
+					Decoders:     currentState.appliedDecoders,
+					Alternatives: currentState.alternatives,
+					Pattern:      f.rule,
+					Rule:         f.rule,
+					Text:         f.text,
+					Offset:       f.offset,
+					SourceOffset: originalOffset(currentState, f.offset, end),
+					Line:         o.line,
+					Record:       o.record,
+					Field:        o.field,
+					Detail:       f.detail,
+				})
+			}
+		}
+
+		// stop if we reached max depth
+		if currentState.depth >= s.Depth {
+			continue
+		}
+
+		// generate next states, decoders that fit the content first
+		for _, name := range prioritizeDecoders(names, currentState.content) {
+			// the first level is always explored in full
+			if currentState.depth > 0 && fruitless[name] {
+				continue
+			}
+			_, brute := solvers[name]
+			if brute && currentState.depth >= s.BruteDepth {
+				continue
+			}
+			if check := applicable[name]; check != nil && !check(currentState.content) {
+				continue
+			}
+			// every solve is bounded, and so is their number
+			if brute && !s.takeSolverRun(&solverRuns) {
+				continue
+			}
+			decoded, err := s.Decoders[name](currentState.content)
+			if attempts != nil {
+				attempts[name]++
+			}
+			if err != nil || decoded == "" || decoded == currentState.content {
+				continue
+			}
+
+			newApplied := make([]string, len(currentState.appliedDecoders), len(currentState.appliedDecoders)+1)
+			copy(newApplied, currentState.appliedDecoders)
+			newApplied = append(newApplied, name)
+
+			if prev, ok := seen[decoded]; ok {
+				// BFS reaches every state at this depth before reporting any
+				// of them, so equally short chains can still be attached;
+				// longer ones are round trips like rot13 -> rot13
+				if prev.depth == currentState.depth+1 {
+					prev.alternatives = append(prev.alternatives, newApplied)
+				}
+				continue
+			}
+
+			next := &searchState{
+				content:         decoded,
+				appliedDecoders: newApplied,
+				depth:           currentState.depth + 1,
+				parent:          currentState,
+			}
+			seen[decoded] = next
+			queue = append(queue, next)
+			if counts != nil {
+				c := counts[name]
+				c.Attempts++
+				counts[name] = c
+			}
+			if trace {
+				s.Logger.Log(ctx, LevelTrace, "decoded", "path", path, "decoders", strings.Join(newApplied, " -> "), "bytes", len(decoded))
+			}
+		}
+	}
+
+	if !matched && ctx.Err() == nil && s.Logger.Enabled(ctx, slog.LevelInfo) {
+		// point at what the decoders could not get through
+		for _, r := range suspiciousRegions(initialContent) {
+			s.Logger.Info("unmatched region", "path", path, "offset", r.Offset, "length", r.Length, "class", r.Class.String())
+		}
+	}
+
+	s.Logger.Debug("scanned", "path", path, "bytes", len(initialContent), "states", len(seen))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// hashString is the hex SHA-256 of s, without copying s into a []byte.
+func hashString(s string) string {
+	h := sha256.New()
+	io.WriteString(h, s)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// countHits credits every decoder on the chains that led to a matching
+// state, once per chain.
+func countHits(counts map[string]decoderCount, state *searchState) {
+	for _, chain := range append([][]string{state.appliedDecoders}, state.alternatives...) {
+		for _, name := range chain {
+			c := counts[name]
+			c.Hits++
+			counts[name] = c
+		}
+	}
+}
+
+// solver runs on one file, or one record of it; with -brute-depth 1 there
+// is only ever one
+const solverRunsPerContent = 4
+
+// takeSolverRun counts a solver run against the limits of the content,
+// whose runs so far are in *runs, and of the scan. It returns false when
+// either is used up.
+func (s *Searcher) takeSolverRun(runs *int) bool {
+	if *runs >= solverRunsPerContent {
+		return false
+	}
+	if s.MaxSolverRuns > 0 {
+		n := s.solverRuns.Add(1)
+		if n > int64(s.MaxSolverRuns) {
+			if n == int64(s.MaxSolverRuns)+1 {
+				s.Logger.Warn("solver limit reached, not running solvers on the remaining content", "max", s.MaxSolverRuns)
+			}
+			return false
+		}
+	}
+	*runs++
+	return true
+}
+
+func (s *Searcher) matches(content string) bool {
+	if s.Regexp == nil {
+		return s.literal.match(content)
+	}
+	return s.Regexp.MatchString(content)
+}
+
+func (s *Searcher) findAll(content string, n int) [][]int {
+	if s.Regexp == nil {
+		return s.literal.findAll(content, n)
+	}
+	return s.Regexp.FindAllStringIndex(content, n)
+}
+
+func (s *Searcher) reportMatches(o origin, fileHash string, state *searchState, found *matchSet) {
+	path := o.path
+	decoders, content := state.appliedDecoders, state.content
+
+	const maxMatchesPerFile = 5
+	matches := s.findAll(content, maxMatchesPerFile+1)
+
+	for i, loc := range matches {
+		if i >= maxMatchesPerFile {
+			found.truncate(path, state)
+			break
+		}
+
+		matchIndex := loc[0]
+		matchEnd := loc[1]
+
+		start := max(matchIndex-s.ContextBefore, 0)
+		end := min(matchEnd+s.ContextAfter, len(content))
+
+		if o.nonASCII && isASCII(content[matchIndex:matchEnd]) {
+			continue
+		}
+
+		line := o.line
+		if line == 0 && len(decoders) == 0 {
+			line = strings.Count(content[:matchIndex], "\n") + 1
+		}
+
+		m := Match{
+			File:     path,
+			FileHash: fileHash,
+			Decoders: decoders,
+			Pattern:  s.expression(),
+			Preset:   s.Preset,
+			Text:     content[matchIndex:matchEnd],
+			Offset:   matchIndex,
+			Line:     line,
+			Record:   o.record,
+			Field:    o.field,
+			// extract from original content
+			Before: content[start:matchIndex],
+			After:  content[matchEnd:end],
+		}
+		m.SourceOffset = originalOffset(state, matchIndex, matchEnd)
+		if s.Explain && len(decoders) > 0 {
+			m.Steps = explainSteps(state, matchIndex, matchEnd)
+		}
+		found.add(m, state)
+	}
+}
+
+// matchSet holds the matches in a content until its search ends, so that
+// the decoder chains reaching the same match, the same text at the same
+// place in the content, make a single one listing them all.
+type matchSet struct {
+	matches []Match
+	states  []*searchState
+	// the other states each match was found in
+	others [][]*searchState
+	index  map[matchKey]int
+	// the states with more matches than were reported
+	truncated []truncation
+}
+
+type truncation struct {
+	path  string
+	state *searchState
+}
+
+type matchKey struct {
+	offset int // in the content the search began with
+	text   string
+}
+
+func newMatchSet() *matchSet {
+	return &matchSet{index: make(map[matchKey]int)}
+}
+
+func (ms *matchSet) add(m Match, state *searchState) {
+	key := matchKey{offset: m.SourceOffset, text: m.Text}
+	if i, ok := ms.index[key]; ok {
+		// the first, shortest chain is reported with the rest as
+		// alternatives
+		ms.others[i] = append(ms.others[i], state)
+		return
+	}
+	ms.index[key] = len(ms.matches)
+	ms.matches = append(ms.matches, m)
+	ms.states = append(ms.states, state)
+	ms.others = append(ms.others, nil)
+}
+
+func (ms *matchSet) truncate(path string, state *searchState) {
+	ms.truncated = append(ms.truncated, truncation{path: path, state: state})
+}
+
+// flush hands the matches to emit, with the chains of the states that
+// produced the same content, which BFS finds after reporting, and of the
+// other states the match was found in as alternatives. The truncations
+// follow the matches, each under the chain its matches were reported with.
+func (ms *matchSet) flush(emit func(Match), truncated func(path string, decoders []string)) {
+	for i, m := range ms.matches {
+		m.Alternatives = slices.Clone(ms.states[i].alternatives)
+		for _, other := range ms.others[i] {
+			m.Alternatives = append(m.Alternatives, other.appliedDecoders)
+			m.Alternatives = append(m.Alternatives, other.alternatives...)
+		}
+		emit(m)
+	}
+	reported := make(map[*searchState]bool)
+	for _, t := range ms.truncated {
+		owner := ms.owner(t.state)
+		if !reported[owner] {
+			reported[owner] = true
+			truncated(t.path, owner.appliedDecoders)
+		}
+	}
+}
+
+// owner returns the state whose chain reports the first match found in
+// state, which is state itself unless that match was found first elsewhere.
+func (ms *matchSet) owner(state *searchState) *searchState {
+	for i, first := range ms.states {
+		if first == state || slices.Contains(ms.others[i], state) {
+			return first
+		}
+	}
+	return state
+}
+
+// emit scores a match and hands it to the output unless it is filtered out.
+func (s *Searcher) emit(m Match) {
+	m.Confidence = confidence(m)
+	if m.Confidence < s.MinConfidence || s.Baseline.Contains(m) || s.Ignore.Contains(m) {
+		return
+	}
+
+	n := s.matchCount.Add(1)
+	if s.MaxResults > 0 && n > int64(s.MaxResults) {
+		// another worker got the last one
+		s.matchCount.Add(-1)
+		return
+	}
+	s.Output.Match(m)
+	if s.MaxResults > 0 && n == int64(s.MaxResults) && s.stop != nil {
+		s.stop(ErrMaxResults)
+	}
+}
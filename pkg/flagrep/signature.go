@@ -0,0 +1,265 @@
+package flagrep
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"debug/macho"
+	"debug/pe"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"time"
+)
+
+// SignatureInfo describes the code signature of a PE or Mach-O file. Only
+// the digest is checked against the file; whether the certificates are
+// trusted and the signature valid is left to the platform's tools.
+type SignatureInfo struct {
+	// "Authenticode", "Mach-O", or "Mach-O ad-hoc" without certificates
+	Kind string `json:"kind"`
+	// common names of the signing certificate and its issuer
+	Signer    string    `json:"signer,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	NotBefore time.Time `json:"not_before,omitzero"`
+	NotAfter  time.Time `json:"not_after,omitzero"`
+	// whether the signed digest is that of the file as it is
+	DigestMatches bool `json:"digest_matches"`
+	// what is unusual, e.g. data appended after the signature
+	Flags []string `json:"flags,omitempty"`
+}
+
+var digestAlgorithms = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+}
+
+// PKCS #7 SignedData, as far as needed to find the signer
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7SignerInfo struct {
+	Version         int
+	IssuerAndSerial struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}
+}
+
+// the SpcIndirectDataContent of Authenticode, holding the file's digest
+type spcIndirectData struct {
+	Data   asn1.RawValue
+	Digest struct {
+		Algorithm struct {
+			Algorithm asn1.ObjectIdentifier
+		}
+		Digest []byte
+	}
+}
+
+// parsePKCS7 returns the signed content and sets the signer of sig from the
+// certificate its first SignerInfo names.
+func parsePKCS7(der []byte, sig *SignatureInfo) ([]byte, bool) {
+	var ci pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, false
+	}
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, false
+	}
+	// one by one, so that a certificate Go cannot parse leaves the others
+	var certs []*x509.Certificate
+	for rest := sd.Certificates.Bytes; len(rest) > 0; {
+		var raw asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &raw); err != nil {
+			break
+		}
+		if c, err := x509.ParseCertificate(raw.FullBytes); err == nil {
+			certs = append(certs, c)
+		}
+	}
+	for _, si := range sd.SignerInfos[:min(len(sd.SignerInfos), 1)] {
+		id := si.IssuerAndSerial
+		for _, c := range certs {
+			if c.SerialNumber.Cmp(id.SerialNumber) == 0 && bytes.Equal(c.RawIssuer, id.Issuer.FullBytes) {
+				sig.Signer = c.Subject.CommonName
+				sig.Issuer = c.Issuer.CommonName
+				sig.NotBefore, sig.NotAfter = c.NotBefore.UTC(), c.NotAfter.UTC()
+			}
+		}
+	}
+	return sd.ContentInfo.Content.Bytes, true
+}
+
+// authenticode reads the signature in the certificate table of a PE file.
+// The digest covers the file but for the checksum, the certificate table's
+// data directory entry and the table itself, which comes last.
+func authenticode(f *pe.File, content []byte) *SignatureInfo {
+	lfanew := int(binary.LittleEndian.Uint32(content[0x3c:]))
+	optional := lfanew + 24
+	dirsAt := optional + 96
+	if _, ok := f.OptionalHeader.(*pe.OptionalHeader64); ok {
+		dirsAt = optional + 112
+	}
+	table, ok := certificateTable(f)
+	if !ok {
+		return nil
+	}
+	start, end := uint64(table.VirtualAddress), uint64(table.VirtualAddress)+uint64(table.Size)
+	checksum, entry := optional+64, dirsAt+8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+	if table.Size < 8 || end > uint64(len(content)) || start < uint64(entry+8) {
+		return nil
+	}
+
+	sig := &SignatureInfo{Kind: "Authenticode"}
+	// WIN_CERTIFICATE: length, revision and type, then the PKCS #7 blob
+	length := uint64(binary.LittleEndian.Uint32(content[start:]))
+	if length < 8 || length > uint64(table.Size) {
+		return nil
+	}
+	der := content[start+8 : start+length]
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil
+	}
+	// the blob and its padding to eight bytes; more is room to hide data
+	// in without breaking the signature
+	if len(bytes.Trim(rest, "\x00")) > 0 || len(rest) >= 8 || length+8 <= uint64(table.Size) {
+		sig.Flags = append(sig.Flags, "data in the certificate table")
+	}
+	if len(bytes.Trim(content[end:], "\x00")) > 0 {
+		sig.Flags = append(sig.Flags, "data after the signature")
+	}
+
+	signed, ok := parsePKCS7(der, sig)
+	var indirect spcIndirectData
+	if ok {
+		_, err = asn1.Unmarshal(signed, &indirect)
+	}
+	hash, known := digestAlgorithms[indirect.Digest.Algorithm.Algorithm.String()]
+	if !ok || err != nil || !known {
+		return sig
+	}
+	h := hash.New()
+	h.Write(content[:checksum])
+	h.Write(content[checksum+4 : entry])
+	h.Write(content[entry+8 : start])
+	sig.DigestMatches = bytes.Equal(h.Sum(nil), indirect.Digest.Digest)
+	return sig
+}
+
+// Mach-O code signature blobs, big-endian
+const (
+	csSuperBlob     = 0xfade0cc0
+	csCodeDirectory = 0xfade0c02
+	csBlobWrapper   = 0xfade0b01
+	// the load command pointing at the signature
+	lcCodeSignature = 0x1d
+)
+
+var csHashes = map[byte]crypto.Hash{1: crypto.SHA1, 2: crypto.SHA256, 3: crypto.SHA256, 4: crypto.SHA384}
+
+// machOSignature reads the code signature of a Mach-O file: a code
+// directory with the hash of every page up to the signature, and a CMS
+// signature over it, empty for ad-hoc signatures.
+func machOSignature(f *macho.File, content []byte) *SignatureInfo {
+	var blob []byte
+	var signedUpTo uint64
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 16 || f.ByteOrder.Uint32(raw) != lcCodeSignature {
+			continue
+		}
+		off, size := uint64(f.ByteOrder.Uint32(raw[8:])), uint64(f.ByteOrder.Uint32(raw[12:]))
+		if off+size <= uint64(len(content)) {
+			blob, signedUpTo = content[off:off+size], off
+		}
+	}
+	if len(blob) < 12 || binary.BigEndian.Uint32(blob) != csSuperBlob {
+		return nil
+	}
+
+	sig := &SignatureInfo{Kind: "Mach-O ad-hoc"}
+	count := binary.BigEndian.Uint32(blob[8:])
+	for i := range min(count, 64) {
+		if 12+8*i+8 > uint32(len(blob)) {
+			break
+		}
+		off := binary.BigEndian.Uint32(blob[12+8*i+4:])
+		if uint64(off)+8 > uint64(len(blob)) {
+			continue
+		}
+		b := blob[off:]
+		length := binary.BigEndian.Uint32(b[4:])
+		if length < 8 || uint64(length) > uint64(len(b)) {
+			continue
+		}
+		b = b[:length]
+		switch binary.BigEndian.Uint32(b) {
+		case csCodeDirectory:
+			// the first code directory; alternates hash with other
+			// algorithms
+			if !sig.DigestMatches {
+				sig.DigestMatches = codeDirectoryMatches(b, content, signedUpTo)
+			}
+		case csBlobWrapper:
+			if length > 8 {
+				sig.Kind = "Mach-O"
+				parsePKCS7(b[8:], sig)
+			}
+		}
+	}
+	if len(bytes.Trim(content[signedUpTo+uint64(len(blob)):], "\x00")) > 0 {
+		sig.Flags = append(sig.Flags, "data after the signature")
+	}
+	return sig
+}
+
+// codeDirectoryMatches checks the page hashes of a code directory against
+// the file, which they must cover up to the signature.
+func codeDirectoryMatches(cd, content []byte, signedUpTo uint64) bool {
+	if len(cd) < 40 {
+		return false
+	}
+	hashOffset := binary.BigEndian.Uint32(cd[16:])
+	slots := binary.BigEndian.Uint32(cd[28:])
+	codeLimit := uint64(binary.BigEndian.Uint32(cd[32:]))
+	hashSize, hashType, pageShift := uint32(cd[36]), cd[37], cd[39]
+	hash, ok := csHashes[hashType]
+	if !ok || codeLimit != signedUpTo || pageShift == 0 || pageShift > 20 ||
+		uint64(hashOffset)+uint64(slots)*uint64(hashSize) > uint64(len(cd)) {
+		return false
+	}
+	page := uint64(1) << pageShift
+	if (codeLimit+page-1)/page != uint64(slots) {
+		return false
+	}
+	for i := range uint64(slots) {
+		h := hash.New()
+		h.Write(content[i*page : min((i+1)*page, codeLimit)])
+		want := cd[uint64(hashOffset)+i*uint64(hashSize):][:hashSize]
+		if !bytes.Equal(h.Sum(nil)[:hashSize], want) {
+			return false
+		}
+	}
+	return true
+}
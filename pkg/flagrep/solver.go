@@ -0,0 +1,266 @@
+package flagrep
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// quadgrams.bin holds the letter quadgrams seen at least twice in the
+// English texts shipped with Go (Newton's Opticks, the Gettysburg address,
+// the memory model) and the common open source licenses, with their counts,
+// as written by quadgrams_gen.go, which also says how to rebuild it.
+//
+//go:embed quadgrams.bin
+var quadgramData []byte
+
+var (
+	quadgramOnce  sync.Once
+	quadgramScore []float64 // log10 probability indexed by quadgramIndex
+)
+
+func quadgramIndex(a, b, c, d byte) int {
+	return ((int(a)*26+int(b))*26+int(c))*26 + int(d)
+}
+
+func loadQuadgrams() {
+	counts := make(map[int]float64)
+	total := 0.0
+	index := 0
+	for data := quadgramData; len(data) > 0; {
+		gap, n := binary.Uvarint(data)
+		if n <= 0 || n >= len(data) {
+			break
+		}
+		index += int(gap)
+		count := math.Exp(float64(data[n]) / 16)
+		counts[index] = count
+		total += count
+		data = data[n+1:]
+	}
+	// unseen quadgrams are rare, not impossible
+	floor := math.Log10(0.01 / total)
+	quadgramScore = make([]float64, 26*26*26*26)
+	for i := range quadgramScore {
+		quadgramScore[i] = floor
+	}
+	for i, count := range counts {
+		quadgramScore[i] = math.Log10(count / total)
+	}
+}
+
+// solvers are the opt-in expensive decoders, see -enable-solver
+var solvers = map[string]DecoderFunc{
+	"substitution": substitutionDecoder,
+}
+
+// EnableSolvers adds the comma-separated solvers in list to decoders.
+func EnableSolvers(decoders map[string]DecoderFunc, list string) error {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		solver, ok := solvers[name]
+		if !ok {
+			return fmt.Errorf("unknown solver %q", name)
+		}
+		decoders[name] = solver
+	}
+	return nil
+}
+
+var errNotSubstitution = errors.New("does not look like substitution ciphertext")
+
+// letters of the ciphertext used to find the key, the key then decodes all
+const solverSampleLetters = 2000
+
+// quadgram lookups one substitution solve may make, a few tenths of a
+// second; long samples get fewer restarts
+const solverBudget = 60_000_000
+
+// text whose quadgrams average above this log10 probability already reads
+// as English words, prose or source code alike; substituted English scores
+// around -7.5
+const englishFitness = -6.5
+
+// substitutionDecoder breaks a monoalphabetic substitution cipher by hill
+// climbing over keys, scoring candidates by English quadgram statistics.
+// Case and non-letters are kept. Only text whose letter frequencies are
+// English-shaped but whose quadgrams are not English is attempted.
+func substitutionDecoder(input string) (string, error) {
+	letters := substitutionSample(input)
+	if letters == nil {
+		return "", errNotSubstitution
+	}
+
+	key := solveSubstitution(letters)
+	out := []byte(input)
+	for i, b := range out {
+		switch {
+		case b >= 'a' && b <= 'z':
+			out[i] = 'a' + key[b-'a']
+		case b >= 'A' && b <= 'Z':
+			out[i] = 'A' + key[b-'A']
+		}
+	}
+	return string(out), nil
+}
+
+// substitutionSample returns the letters (0-25) of input to find the key
+// with, nil when input does not look like substitution ciphertext.
+func substitutionSample(input string) []byte {
+	var letters []byte
+	for i := 0; i < len(input) && len(letters) < solverSampleLetters; i++ {
+		if b := input[i] | 0x20; b >= 'a' && b <= 'z' {
+			letters = append(letters, b-'a')
+		}
+	}
+	// shorter texts do not pin down the key
+	if len(letters) < 80 || printableRatio(input) < 0.95 || englishScore(input) < englishChi2 {
+		return nil
+	}
+	// a substitution keeps the index of coincidence of English (~0.066)
+	if ic, _ := letterStats(input); ic < 0.055 {
+		return nil
+	}
+	quadgramOnce.Do(loadQuadgrams)
+	if quadgramFitness(letters) > englishFitness {
+		return nil
+	}
+	return letters
+}
+
+// quadgramFitness returns the average log10 probability of the quadgrams
+// of letters (0-25).
+func quadgramFitness(letters []byte) float64 {
+	if len(letters) < 4 {
+		return math.Inf(-1)
+	}
+	total := 0.0
+	for i := 0; i+3 < len(letters); i++ {
+		total += quadgramScore[quadgramIndex(letters[i], letters[i+1], letters[i+2], letters[i+3])]
+	}
+	return total / float64(len(letters)-3)
+}
+
+// solveSubstitution returns the key mapping cipher letters (0-25) to plain
+// letters that scores best over a few random restarts, within solverBudget.
+func solveSubstitution(cipher []byte) [26]byte {
+	quadgramOnce.Do(loadQuadgrams)
+
+	// fixed seed, the same input must give the same output
+	rng := rand.New(rand.NewPCG(1, 2))
+	plain := make([]byte, len(cipher))
+	lookups := 0
+	score := func(key *[26]byte) float64 {
+		lookups += len(plain)
+		for i, c := range cipher {
+			plain[i] = key[c]
+		}
+		total := 0.0
+		for i := 0; i+3 < len(plain); i++ {
+			total += quadgramScore[quadgramIndex(plain[i], plain[i+1], plain[i+2], plain[i+3])]
+		}
+		return total
+	}
+	exhausted := func() bool { return lookups >= solverBudget }
+
+	var best [26]byte
+	bestScore := math.Inf(-1)
+	const restarts, patience = 60, 1000
+	for restart := 0; restart < restarts && !exhausted(); restart++ {
+		var key [26]byte
+		if restart == 0 {
+			key = frequencyKey(cipher)
+		} else {
+			for i, p := range rng.Perm(26) {
+				key[i] = byte(p)
+			}
+		}
+		current := score(&key)
+		for stale := 0; stale < patience && !exhausted(); stale++ {
+			a, b := rng.IntN(26), rng.IntN(26)
+			key[a], key[b] = key[b], key[a]
+			if s := score(&key); s > current {
+				current, stale = s, 0
+				continue
+			}
+			key[a], key[b] = key[b], key[a]
+		}
+		if current > bestScore {
+			best, bestScore = key, current
+		}
+	}
+	polishKey(&best, bestScore, score, exhausted)
+	return best
+}
+
+// frequencyKey maps the most frequent cipher letter to e, the next to t
+// and so on.
+func frequencyKey(cipher []byte) [26]byte {
+	const englishOrder = "etaoinshrdlcumwfgypbvkjxqz"
+	var counts [26]int
+	for _, c := range cipher {
+		counts[c]++
+	}
+	order := make([]byte, 26)
+	for i := range order {
+		order[i] = byte(i)
+	}
+	slices.SortStableFunc(order, func(a, b byte) int { return counts[b] - counts[a] })
+	var key [26]byte
+	for rank, c := range order {
+		key[c] = englishOrder[rank] - 'a'
+	}
+	return key
+}
+
+// passes over all swaps and rotations in polishKey, each costs some 16,000
+// scorings of the sample
+const polishPasses = 5
+
+// polishKey tries every swap and every rotation of three letters of key
+// until none improves the score, polishPasses are done or exhausted says
+// so; random swaps get stuck when three letters are rotated.
+func polishKey(key *[26]byte, current float64, score func(*[26]byte) float64, exhausted func() bool) float64 {
+	for pass, improved := 0, true; improved && pass < polishPasses; pass++ {
+		improved = false
+		for a := 0; a < 26; a++ {
+			for b := 0; b < 26; b++ {
+				if b == a {
+					continue
+				}
+				if exhausted() {
+					return current
+				}
+				if a < b {
+					key[a], key[b] = key[b], key[a]
+					if s := score(key); s > current {
+						current, improved = s, true
+						continue
+					}
+					key[a], key[b] = key[b], key[a]
+				}
+				for c := 0; c < 26; c++ {
+					if c == a || c == b {
+						continue
+					}
+					key[a], key[b], key[c] = key[b], key[c], key[a]
+					if s := score(key); s > current {
+						current, improved = s, true
+						continue
+					}
+					key[a], key[b], key[c] = key[c], key[a], key[b]
+				}
+			}
+		}
+	}
+	return current
+}
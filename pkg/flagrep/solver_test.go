@@ -0,0 +1,113 @@
+package flagrep
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+const solverPlaintext = "It was the best of times, it was the worst of times, it was the age of wisdom, " +
+	"it was the age of foolishness, it was the epoch of belief, it was the epoch of incredulity, " +
+	"it was the season of light, it was the season of darkness. The flag is hidden in plain sight " +
+	"and whoever reads this message carefully will surely find the answer they are looking for."
+
+func TestSubstitutionDecoder(t *testing.T) {
+	const key = "qwertyuiopasdfghjklzxcvbnm"
+	cipher := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return rune(key[r-'a'])
+		case r >= 'A' && r <= 'Z':
+			return rune(key[r-'A'] - 'a' + 'A')
+		}
+		return r
+	}, solverPlaintext)
+
+	got, err := substitutionDecoder(cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rare letters may stay swapped, the common words must not
+	if !strings.Contains(got, "it was the age of wisdom") || !strings.Contains(got, "The flag is hidden") {
+		t.Errorf("not solved: %q", got)
+	}
+
+	if _, err := substitutionDecoder(solverPlaintext); err == nil {
+		t.Error("expected plain English to be left alone")
+	}
+	if _, err := substitutionDecoder("xqzv"); err == nil {
+		t.Error("expected short input to be rejected")
+	}
+	// source code is not English enough by letter frequency, but its words are
+	source, err := os.ReadFile("search.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if substitutionSample(string(source)) != nil {
+		t.Error("expected source code to be left alone")
+	}
+}
+
+func TestSolverRunLimits(t *testing.T) {
+	var calls int
+	solvers["fake"] = func(s string) (string, error) {
+		calls++
+		return "", errNotSubstitution
+	}
+	defer delete(solvers, "fake")
+
+	searcher := NewSearcher(nil, "nothing", false, true, 1, 2, 0, 0, false)
+	searcher.Logger = slog.New(slog.DiscardHandler)
+	searcher.Decoders = map[string]DecoderFunc{"reverse": reverseDecoder, "rot13": rot13Decoder, "rot47": rot47Decoder,
+		"space_removal": spaceRemovalDecoder, "fake": solvers["fake"]}
+	searcher.BruteDepth = 2
+	// the root and four states below it
+	searcher.searchBFS(context.Background(), "a b c", "input")
+	if calls != solverRunsPerContent {
+		t.Errorf("solver ran %d times on one file, want %d", calls, solverRunsPerContent)
+	}
+
+	calls = 0
+	searcher.MaxSolverRuns = solverRunsPerContent + 2
+	searcher.searchBFS(context.Background(), "d e f", "input")
+	if calls != 2 {
+		t.Errorf("solver ran %d times after the scan limit, want 2", calls)
+	}
+}
+
+func TestEnableSolvers(t *testing.T) {
+	decoders := Decoders()
+	if err := EnableSolvers(decoders, "substitution"); err != nil {
+		t.Fatal(err)
+	}
+	if decoders["substitution"] == nil {
+		t.Error("substitution solver not enabled")
+	}
+	if err := EnableSolvers(decoders, "vigenere"); err == nil {
+		t.Error("expected an unknown solver to be rejected")
+	}
+}
+
+func TestBruteDepth(t *testing.T) {
+	// a stand-in solver that records the depth of the states it gets
+	var calls []string
+	solvers["fake"] = func(s string) (string, error) {
+		calls = append(calls, s)
+		return "", errNotSubstitution
+	}
+	defer delete(solvers, "fake")
+
+	for _, c := range []struct{ bruteDepth, want int }{{0, 0}, {1, 1}, {2, 2}} {
+		calls = nil
+		searcher := NewSearcher(nil, "nothing", false, true, 1, 2, 0, 0, false)
+		searcher.Decoders = map[string]DecoderFunc{"reverse": reverseDecoder, "fake": solvers["fake"]}
+		searcher.BruteDepth = c.bruteDepth
+		searcher.searchBFS(context.Background(), "abc", "input")
+		// depth 0 is "abc", depth 1 adds "cba"
+		if len(calls) != c.want {
+			t.Errorf("brute depth %d: solver ran on %q, want %d states", c.bruteDepth, calls, c.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package flagrep
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// files with less than 1/sparseRatio of their size allocated on disk are
+// mostly holes; above sparseMinSize, reading them is gigabytes of zeros
+const (
+	sparseRatio   = 64
+	sparseMinSize = 64 << 20
+)
+
+// specialFileReason tells why a file named or met in a directory walk is
+// not to be read, "" for a regular file. Reading a FIFO blocks until someone writes
+// to it and devices like /dev/zero never end, so either would hang a
+// worker. Symbolic links are judged by their target.
+func specialFileReason(path string, info fs.FileInfo) string {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Stat(path)
+		if err != nil {
+			return err.Error()
+		}
+		info = target
+	}
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		return "symbolic link to a directory, not followed"
+	case mode&fs.ModeDevice != 0:
+		return "device file"
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case !mode.IsRegular():
+		return "irregular file"
+	}
+	if allocated, ok := allocatedSize(info); ok && info.Size() > sparseMinSize && allocated*sparseRatio < info.Size() {
+		return fmt.Sprintf("sparse file, %d of %d bytes allocated", allocated, info.Size())
+	}
+	return ""
+}
+
+// InsideRoot tells whether path, with every symbolic link in it resolved,
+// is root or inside it. Paths that cannot be resolved are not.
+func InsideRoot(root, path string) bool {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realRoot, real)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package flagrep
+
+import "io/fs"
+
+// allocatedSize is not known here, so no file counts as sparse.
+func allocatedSize(info fs.FileInfo) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,84 @@
+//go:build linux || darwin || freebsd
+
+package flagrep
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSpecialFilesSkipped(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "regular.txt"), []byte("flag{x}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// socket paths are limited to about 100 bytes, TempDir can be longer
+	if l, err := net.Listen("unix", filepath.Join(dir, "sock")); err == nil {
+		defer l.Close()
+	}
+	if err := os.Symlink("/dev/zero", filepath.Join(dir, "zero")); err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := os.Create(filepath.Join(dir, "sparse.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sparse.Truncate(1 << 30); err != nil {
+		t.Fatal(err)
+	}
+	sparse.Close()
+
+	searcher := NewSearcher([]string{dir}, "flag{", false, true, 1, 1, 0, 0, false)
+	var visited []string
+	skipped := make(map[string]string)
+	err = searcher.walkFiles(t.Context(), dir, func(path string) {
+		visited = append(visited, filepath.Base(path))
+	}, func(path, reason string) {
+		skipped[filepath.Base(path)] = reason
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 1 || visited[0] != "regular.txt" {
+		t.Errorf("visited %q, want only regular.txt", visited)
+	}
+	for name, reason := range map[string]string{"pipe": "named pipe", "zero": "device file"} {
+		if skipped[name] != reason {
+			t.Errorf("%s skipped for %q, want %q", name, skipped[name], reason)
+		}
+	}
+	if _, ok := skipped["sock"]; !ok {
+		if _, err := os.Lstat(filepath.Join(dir, "sock")); err == nil {
+			t.Error("socket not skipped")
+		}
+	}
+	if skipped["sparse.img"] == "" {
+		// tmpfs and most disk file systems support holes, not all do
+		t.Logf("sparse file not detected: %v", skipped)
+	}
+
+	// named explicitly, they are skipped all the same, and listing them
+	// must not open the pipe
+	for _, root := range []string{filepath.Join(dir, "pipe"), "/dev/zero"} {
+		var out strings.Builder
+		searcher := NewSearcher([]string{root}, "flag{", false, true, 1, 1, 0, 0, false)
+		done := make(chan error)
+		go func() { done <- searcher.ListFiles(&out) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("listing %s hangs", root)
+		}
+		if !strings.HasPrefix(out.String(), "skip "+root+": ") {
+			t.Errorf("listing %s: %q", root, out.String())
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package flagrep
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// allocatedSize returns the bytes a file takes up on disk.
+func allocatedSize(info fs.FileInfo) (int64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(st.Blocks) * 512, true
+}
@@ -0,0 +1,190 @@
+package flagrep
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/pe"
+	"encoding/binary"
+	"slices"
+	"strings"
+)
+
+// instructions further apart than this end a stack string
+const stackStringMaxGap = 16
+
+// executableSections returns the code of an ELF or PE file, nil for anything
+// else.
+func executableSections(content []byte) [][]byte {
+	var code [][]byte
+	if f, err := elf.NewFile(bytes.NewReader(content)); err == nil {
+		for _, s := range f.Sections {
+			if s.Flags&elf.SHF_EXECINSTR != 0 && s.Type != elf.SHT_NOBITS {
+				if data, err := s.Data(); err == nil {
+					code = append(code, data)
+				}
+			}
+		}
+		return code
+	}
+	if f, err := pe.NewFile(bytes.NewReader(content)); err == nil {
+		for _, s := range f.Sections {
+			if s.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+				if data, err := s.Data(); err == nil {
+					code = append(code, data)
+				}
+			}
+		}
+	}
+	return code
+}
+
+// stackStore is an immediate stored to a stack slot.
+type stackStore struct {
+	base byte // ModRM r/m: 5 for rbp, 4 for rsp
+	disp int32
+	data []byte
+}
+
+// decodeStackStore recognizes the x86/x64 instructions compilers emit to build
+// strings on the stack, one immediate at a time:
+//
+//	C6 45 d8 ib / C6 85 d32 ib          mov byte [ebp+d], imm8
+//	C6 44 24 d8 ib / C6 84 24 d32 ib    mov byte [esp+d], imm8
+//	C7 45 d8 id / C7 85 d32 id          mov dword [ebp+d], imm32
+//	C7 44 24 d8 id / C7 84 24 d32 id    mov dword [esp+d], imm32
+//
+// optionally prefixed by 66 (word immediates) or REX.W (qword stores of a
+// sign-extended imm32, of which we keep the low four bytes). It returns the
+// store and the instruction length, or length 0 if code does not start with
+// such an instruction.
+func decodeStackStore(code []byte) (stackStore, int) {
+	i := 0
+	immSize := 4
+	switch {
+	case len(code) > 0 && code[0] == 0x66:
+		immSize = 2
+		i++
+	case len(code) > 0 && code[0]&0xf8 == 0x48:
+		i++
+	}
+	if i >= len(code) || (code[i] != 0xc6 && code[i] != 0xc7) {
+		return stackStore{}, 0
+	}
+	if code[i] == 0xc6 {
+		if i > 0 {
+			return stackStore{}, 0
+		}
+		immSize = 1
+	}
+	i++
+	if i >= len(code) {
+		return stackStore{}, 0
+	}
+
+	modrm := code[i]
+	i++
+	mod, rm := modrm>>6, modrm&7
+	if modrm>>3&7 != 0 || (mod != 1 && mod != 2) {
+		return stackStore{}, 0
+	}
+	switch rm {
+	case 5: // [rbp+disp]
+	case 4: // SIB, only [rsp+disp]
+		if i >= len(code) || code[i] != 0x24 {
+			return stackStore{}, 0
+		}
+		i++
+	default:
+		return stackStore{}, 0
+	}
+
+	var disp int32
+	if mod == 1 {
+		if i >= len(code) {
+			return stackStore{}, 0
+		}
+		disp = int32(int8(code[i]))
+		i++
+	} else {
+		if i+4 > len(code) {
+			return stackStore{}, 0
+		}
+		disp = int32(binary.LittleEndian.Uint32(code[i:]))
+		i += 4
+	}
+
+	if i+immSize > len(code) {
+		return stackStore{}, 0
+	}
+	data := code[i : i+immSize]
+	return stackStore{base: rm, disp: disp, data: data}, i + immSize
+}
+
+// StackStrings reconstructs strings that code builds on the stack with runs
+// of immediate stores, returning those of at least minLen printable bytes.
+func StackStrings(code []byte, minLen int) []string {
+	var result []string
+	var run []stackStore
+	gap := 0
+
+	flush := func() {
+		if len(run) > 1 {
+			result = append(result, assembleStackString(run, minLen)...)
+		}
+		run = run[:0]
+	}
+
+	for i := 0; i < len(code); {
+		store, n := decodeStackStore(code[i:])
+		if n == 0 {
+			i++
+			gap++
+			if gap > stackStringMaxGap {
+				flush()
+			}
+			continue
+		}
+		// offsets from different base registers do not line up
+		if len(run) > 0 && run[0].base != store.base {
+			flush()
+		}
+		run = append(run, store)
+		gap = 0
+		i += n
+	}
+	flush()
+	return result
+}
+
+// assembleStackString lays the stores out by displacement and returns the
+// printable strings in the resulting buffer.
+func assembleStackString(run []stackStore, minLen int) []string {
+	slices.SortStableFunc(run, func(a, b stackStore) int { return int(a.disp) - int(b.disp) })
+
+	lo := run[0].disp
+	hi := run[len(run)-1].disp + int32(len(run[len(run)-1].data))
+	// a sane stack frame, not a coincidental decode with far apart slots
+	if hi-lo > 4096 {
+		return nil
+	}
+	buf := make([]byte, hi-lo)
+	for _, st := range run {
+		copy(buf[st.disp-lo:], st.data)
+	}
+
+	var result []string
+	for _, s := range ExtractStrings(buf, minLen) {
+		result = append(result, s.Text)
+	}
+	return result
+}
+
+// stackStringsContent returns the stack strings of an executable joined into
+// one searchable text, or "" if there are none.
+func stackStringsContent(content []byte) string {
+	var found []string
+	for _, code := range executableSections(content) {
+		found = append(found, StackStrings(code, 4)...)
+	}
+	return strings.Join(found, "\n")
+}
@@ -0,0 +1,47 @@
+package flagrep
+
+import "testing"
+
+func TestStackStrings(t *testing.T) {
+	code := []byte{
+		0x55,             // push rbp
+		0x48, 0x89, 0xe5, // mov rbp, rsp
+		0xc7, 0x45, 0xf0, 'f', 'l', 'a', 'g', // mov dword [rbp-0x10], "flag"
+		0xc6, 0x45, 0xf4, '{', // mov byte [rbp-0xc], '{'
+		0x66, 0xc7, 0x45, 0xf5, 'x', '}', // mov word [rbp-0xb], "x}"
+		0xc6, 0x45, 0xf7, 0x00, // mov byte [rbp-0x9], 0
+		0xc3, // ret
+	}
+
+	found := StackStrings(code, 4)
+	if len(found) != 1 || found[0] != "flag{x}" {
+		t.Errorf("expected flag{x}, got %q", found)
+	}
+}
+
+func TestStackStringsOutOfOrder(t *testing.T) {
+	// compilers do not always store the slots in ascending order
+	code := []byte{
+		0xc6, 0x45, 0xf2, 'c',
+		0xc6, 0x45, 0xf0, 'a',
+		0xc6, 0x45, 0xf3, 'd',
+		0xc6, 0x45, 0xf1, 'b',
+	}
+	found := StackStrings(code, 4)
+	if len(found) != 1 || found[0] != "abcd" {
+		t.Errorf("expected abcd, got %q", found)
+	}
+}
+
+func TestStackStringsRSP(t *testing.T) {
+	code := []byte{
+		0xc6, 0x44, 0x24, 0x10, 'k', // mov byte [rsp+0x10], 'k'
+		0xc6, 0x44, 0x24, 0x11, 'e',
+		0xc6, 0x44, 0x24, 0x12, 'y',
+		0xc6, 0x44, 0x24, 0x13, 's',
+	}
+	found := StackStrings(code, 4)
+	if len(found) != 1 || found[0] != "keys" {
+		t.Errorf("expected keys, got %q", found)
+	}
+}
@@ -0,0 +1,59 @@
+package flagrep
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// UPXRunner unpacks UPX-packed executables with an installed upx, so that
+// the code and strings compressed inside are searched as well.
+type UPXRunner struct {
+	Bin string
+}
+
+func NewUPXRunner(bin string) (*UPXRunner, error) {
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return nil, fmt.Errorf("upx not found: %w", err)
+	}
+	return &UPXRunner{Bin: path}, nil
+}
+
+// upxPacked tells whether content is an executable carrying the "UPX!"
+// header that upx writes next to the file header.
+func upxPacked(content []byte) bool {
+	magic := false
+	for _, m := range []string{"MZ", "\x7fELF", "\xfe\xed\xfa\xce", "\xce\xfa\xed\xfe", "\xfe\xed\xfa\xcf", "\xcf\xfa\xed\xfe"} {
+		magic = magic || bytes.HasPrefix(content, []byte(m))
+	}
+	return magic && bytes.Contains(content[:min(len(content), 4096)], []byte("UPX!"))
+}
+
+// Unpack returns content as upx -d restores it. upx only works on files, so
+// content goes through a temporary directory.
+func (u *UPXRunner) Unpack(content []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "flagrep-upx-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	packed, unpacked := filepath.Join(dir, "packed"), filepath.Join(dir, "unpacked")
+	if err := os.WriteFile(packed, content, 0600); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(u.Bin, "-d", "-q", "-o", unpacked, packed)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return os.ReadFile(unpacked)
+}
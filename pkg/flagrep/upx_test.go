@@ -0,0 +1,49 @@
+package flagrep
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestUPXRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as fake upx binary")
+	}
+	dir := t.TempDir()
+
+	// stands in for upx -d -q -o OUT IN: writes what the packed file hides
+	fake := filepath.Join(dir, "upx")
+	script := "#!/bin/sh\nprintf 'unpacked flag{upx}' > \"$4\"\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	packed := filepath.Join(dir, "packed.exe")
+	if err := os.WriteFile(packed, []byte("MZ\x90\x00 UPX! compressed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain.exe")
+	if err := os.WriteFile(plain, []byte("MZ\x90\x00 nothing packed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := NewUPXRunner(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searcher := NewSearcher([]string{packed, plain}, "flag{", false, true, 1, 0, 4, 4, false)
+	searcher.UPX = runner
+	out := &collectOutput{}
+	searcher.Output = out
+	if err := searcher.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.matches) != 1 || out.matches[0].File != packed+" (upx unpacked)" {
+		t.Errorf("expected the unpacked flag only, got %+v", out.matches)
+	}
+
+	if _, err := NewUPXRunner(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing upx to be an error")
+	}
+}
@@ -0,0 +1,114 @@
+package flagrep
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// with automatic concurrency, workers are added up to this many per CPU
+	autoScaleFactor = 4
+	// and only while files are small enough that reading them, not
+	// decoding, is what keeps the workers busy
+	autoScaleMaxMeanSize = 1 << 20
+)
+
+// WorkerStats is the throughput of one worker of a scan.
+type WorkerStats struct {
+	ID    int
+	Files int64
+	Bytes int64
+	// time spent reading and searching files, not waiting for them
+	Busy time.Duration
+}
+
+// workerPool runs processFile for the paths the walk submits. With a fixed
+// Concurrency it starts that many workers; with 0 it starts one per CPU and
+// adds more while the walk finds them all busy and files are small.
+type workerPool struct {
+	ctx   context.Context
+	s     *Searcher
+	files chan string
+	wg    sync.WaitGroup
+	max   int
+	// only touched by the goroutine submitting paths
+	workers []*WorkerStats
+
+	scannedFiles atomic.Int64
+	scannedBytes atomic.Int64
+}
+
+func (s *Searcher) newWorkerPool(ctx context.Context) *workerPool {
+	p := &workerPool{ctx: ctx, s: s, files: make(chan string)}
+	n := s.Concurrency
+	p.max = n
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+		p.max = n * autoScaleFactor
+	}
+	for range n {
+		p.grow()
+	}
+	return p
+}
+
+func (p *workerPool) grow() {
+	w := &WorkerStats{ID: len(p.workers) + 1}
+	p.workers = append(p.workers, w)
+	p.wg.Go(func() {
+		for path := range p.files {
+			if p.ctx.Err() != nil {
+				// drain so the walk is not blocked
+				continue
+			}
+			start := time.Now()
+			size := p.s.processFile(p.ctx, path)
+			w.Busy += time.Since(start)
+			if size >= 0 {
+				w.Files++
+				w.Bytes += int64(size)
+				p.scannedFiles.Add(1)
+				p.scannedBytes.Add(int64(size))
+			}
+		}
+	})
+}
+
+// submit hands path to an idle worker, adding one first if all are busy
+// and the pool may grow.
+func (p *workerPool) submit(path string) {
+	select {
+	case p.files <- path:
+		return
+	case <-p.ctx.Done():
+		return
+	default:
+	}
+	if len(p.workers) < p.max && p.smallFiles() {
+		p.grow()
+		p.s.Logger.Debug("added worker", "workers", len(p.workers))
+	}
+	select {
+	case p.files <- path:
+	case <-p.ctx.Done():
+	}
+}
+
+func (p *workerPool) smallFiles() bool {
+	n := p.scannedFiles.Load()
+	return n == 0 || p.scannedBytes.Load()/n < autoScaleMaxMeanSize
+}
+
+// wait lets the workers finish and returns their stats.
+func (p *workerPool) wait() []WorkerStats {
+	close(p.files)
+	p.wg.Wait()
+	stats := make([]WorkerStats, len(p.workers))
+	for i, w := range p.workers {
+		stats[i] = *w
+	}
+	return stats
+}
@@ -0,0 +1,51 @@
+package flagrep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWorkerPool(t *testing.T) {
+	dir := t.TempDir()
+	const files = 200
+	for i := range files {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.txt", i)), []byte("flag{x}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct{ concurrency, min, max int }{
+		{3, 3, 3},
+		{0, runtime.GOMAXPROCS(0), runtime.GOMAXPROCS(0) * autoScaleFactor},
+	} {
+		var summary ScanSummary
+		searcher := NewSearcher([]string{dir}, "flag{", false, true, c.concurrency, 1, 0, 0, false)
+		searcher.Output = &summaryOutput{summary: &summary}
+		if err := searcher.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(summary.Workers); n < c.min || n > c.max {
+			t.Errorf("concurrency %d: %d workers, want %d to %d", c.concurrency, n, c.min, c.max)
+		}
+		var total, bytes int64
+		for _, w := range summary.Workers {
+			total += w.Files
+			bytes += w.Bytes
+		}
+		if total != files || bytes != files*int64(len("flag{x}")) {
+			t.Errorf("concurrency %d: workers scanned %d files and %d bytes", c.concurrency, total, bytes)
+		}
+	}
+}
+
+type summaryOutput struct {
+	discardOutput
+	summary *ScanSummary
+}
+
+func (o *summaryOutput) End(summary ScanSummary) {
+	*o.summary = summary
+}
@@ -0,0 +1,191 @@
+package flagrep
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// YaraRunner runs rules through an installed YARA engine, either the classic
+// yara binary or yara-x's yr, for full rule-language coverage.
+type YaraRunner struct {
+	Bin   string
+	Rules string
+	// Rules compiled once by yarac or "yr compile", "" when no compiler was
+	// found and every scan compiles the source again
+	compiled string
+}
+
+// YaraMatch is one matched string reported by the engine.
+type YaraMatch struct {
+	Rule     string
+	StringID string
+	Offset   int
+	Length   int
+	Data     string
+}
+
+func NewYaraRunner(bin, rules string) (*YaraRunner, error) {
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return nil, fmt.Errorf("yara engine not found: %w", err)
+	}
+	y := &YaraRunner{Bin: path, Rules: rules}
+	if err := y.compile(); err != nil {
+		return nil, err
+	}
+	return y, nil
+}
+
+// compile compiles the rules into a temporary file so that a recursive scan
+// doesn't parse them again for every file. Without a compiler the rules are
+// left as source.
+func (y *YaraRunner) compile() error {
+	var compiler string
+	if !y.isYaraX() {
+		// prefer the yarac that came with the yara we run
+		compiler = filepath.Join(filepath.Dir(y.Bin), "yarac"+filepath.Ext(y.Bin))
+		if _, err := exec.LookPath(compiler); err != nil {
+			if compiler, err = exec.LookPath("yarac"); err != nil {
+				return nil
+			}
+		}
+	}
+
+	f, err := os.CreateTemp("", "flagrep-*.yarc")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	cmd := exec.Command(compiler, y.Rules, f.Name())
+	if y.isYaraX() {
+		cmd = exec.Command(y.Bin, "compile", y.Rules, "-o", f.Name())
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(f.Name())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("compiling %s: %v: %s", y.Rules, err, msg)
+		}
+		return fmt.Errorf("compiling %s: %v", y.Rules, err)
+	}
+	y.compiled = f.Name()
+	return nil
+}
+
+// Close removes the compiled rules.
+func (y *YaraRunner) Close() error {
+	if y.compiled == "" {
+		return nil
+	}
+	err := os.Remove(y.compiled)
+	y.compiled = ""
+	return err
+}
+
+func (y *YaraRunner) isYaraX() bool {
+	name := strings.TrimSuffix(filepath.Base(y.Bin), filepath.Ext(y.Bin))
+	return name == "yr" || name == "yara-x"
+}
+
+// Scan runs the rules against the file at path.
+func (y *YaraRunner) Scan(path string) ([]YaraMatch, error) {
+	// -L for the real length of each string: yara escapes non-printable
+	// bytes and cuts long strings short in what it prints
+	args := []string{"-s", "-L", "-w"}
+	if y.isYaraX() {
+		args = []string{"scan", "-s", "--disable-console-logs"}
+	}
+	rules := y.Rules
+	if y.compiled != "" {
+		args = append(args, "-C")
+		rules = y.compiled
+	}
+	args = append(args, rules, path)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(y.Bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return parseYaraOutput(stdout.String()), nil
+}
+
+// parseYaraOutput reads the output of "yara -s -L" and "yr scan -s":
+//
+//	RuleName /path/to/file
+//	0x1a:11:$s1: matched\x00data     (yara)
+//	0x1a:11:$s1: "matched data"     (yara-x)
+//
+// Older output without the length, "0x1a:$s1: matched data", is still
+// accepted; its length is only right for short printable strings.
+//
+// Rules that match only on their condition produce no string lines and are
+// reported with an empty StringID.
+func parseYaraOutput(out string) []YaraMatch {
+	var matches []YaraMatch
+	rule := ""
+	ruleHasStrings := false
+
+	flushRule := func() {
+		if rule != "" && !ruleHasStrings {
+			matches = append(matches, YaraMatch{Rule: rule})
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "0x") {
+			flushRule()
+			rule, _, _ = strings.Cut(line, " ")
+			ruleHasStrings = false
+			continue
+		}
+		if rule == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 4)
+		if len(parts) < 3 {
+			continue
+		}
+		offset, err := strconv.ParseInt(parts[0], 0, 64)
+		if err != nil {
+			continue
+		}
+		m := YaraMatch{Rule: rule, Offset: int(offset)}
+		if strings.HasPrefix(strings.TrimSpace(parts[1]), "$") {
+			// yara: offset:$id: data
+			m.StringID = strings.TrimSpace(parts[1])
+			m.Data = strings.TrimPrefix(strings.Join(parts[2:], ":"), " ")
+			m.Length = len(m.Data)
+		} else if len(parts) == 4 {
+			// offset:length:$id: data, quoted by yara-x
+			length, _ := strconv.Atoi(parts[1])
+			m.Length = length
+			m.StringID = strings.TrimSpace(parts[2])
+			m.Data = strings.TrimSpace(parts[3])
+			if unquoted, err := strconv.Unquote(m.Data); err == nil {
+				m.Data = unquoted
+			}
+		} else {
+			continue
+		}
+		matches = append(matches, m)
+		ruleHasStrings = true
+	}
+	flushRule()
+	return matches
+}
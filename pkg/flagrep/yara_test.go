@@ -0,0 +1,105 @@
+package flagrep
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseYaraOutput(t *testing.T) {
+	classic := "Secrets /tmp/a.bin\n0x1a:$key: AKIAEXAMPLE\nOnlyCondition /tmp/a.bin\n"
+	matches := parseYaraOutput(classic)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	if m := matches[0]; m.Rule != "Secrets" || m.StringID != "$key" || m.Offset != 0x1a || m.Data != "AKIAEXAMPLE" || m.Length != 11 {
+		t.Errorf("unexpected yara match: %+v", m)
+	}
+	if m := matches[1]; m.Rule != "OnlyCondition" || m.StringID != "" {
+		t.Errorf("unexpected condition-only match: %+v", m)
+	}
+
+	// yara -L: escaped data, real length
+	matches = parseYaraOutput("Secrets /tmp/a.bin\n0x4:4:$s: \\x00ab:\n")
+	if len(matches) != 1 || matches[0].StringID != "$s" || matches[0].Length != 4 || matches[0].Data != "\\x00ab:" {
+		t.Errorf("unexpected yara -L matches: %+v", matches)
+	}
+
+	yarax := "Secrets /tmp/a.bin\n0x1a:11:$key: \"AKIA:EXAMPLE\"\n"
+	matches = parseYaraOutput(yarax)
+	if len(matches) != 1 || matches[0].Data != "AKIA:EXAMPLE" || matches[0].Length != 11 {
+		t.Errorf("unexpected yara-x matches: %+v", matches)
+	}
+}
+
+func TestYaraRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as fake yara binary")
+	}
+	tmpDir, err := os.MkdirTemp("", "encodedgrep_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// stands in for yara: logs its arguments and prints a match for whatever
+	// file it is given, escaped the way yara prints non-printable bytes
+	fake := filepath.Join(tmpDir, "yara")
+	script := "#!/bin/sh\necho \"$@\" >> \"$0.log\"\nfor last; do :; done\necho \"Demo $last\"\necho '0x3:6:$s: \\x00flag'\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// and for yarac next to it
+	compiler := "#!/bin/sh\necho \"$@\" >> \"$0.log\"\necho compiled > \"$2\"\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "yarac"), []byte(compiler), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, name := range []string{"a.bin", "b.bin"} {
+		file := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(file, []byte("xxx\x00flag{y} xxx"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	runner, err := NewYaraRunner(fake, "rules.yar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := runner.compiled
+	if compiled == "" {
+		t.Fatal("rules were not compiled")
+	}
+	searcher := NewSearcher(files, "nomatch", false, true, 1, 0, 4, 4, false)
+	searcher.Yara = runner
+	out := &collectOutput{}
+	searcher.Output = out
+	if err := searcher.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out.matches) != 2 {
+		t.Fatalf("expected two yara matches, got %+v", out.matches)
+	}
+	m := out.matches[0]
+	if m.Rule != "Demo" || m.Text != "\x00flag{" || m.Before != "xxx" || m.After != "y} x" {
+		t.Errorf("unexpected match: %+v", m)
+	}
+
+	compilerLog, _ := os.ReadFile(filepath.Join(tmpDir, "yarac.log"))
+	if n := strings.Count(string(compilerLog), "\n"); n != 1 {
+		t.Errorf("rules compiled %d times", n)
+	}
+	scanLog, _ := os.ReadFile(fake + ".log")
+	if !strings.Contains(string(scanLog), "-L -w -C "+compiled) {
+		t.Errorf("scan did not use the compiled rules: %s", scanLog)
+	}
+
+	runner.Close()
+	if _, err := os.Stat(compiled); !os.IsNotExist(err) {
+		t.Errorf("compiled rules left behind: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRuleID  = "flagrep/encoded-match"
+)
+
+// sarifOutput collects matches and writes a single SARIF 2.1.0 log when the
+// scan ends, for upload to GitHub code scanning and similar dashboards.
+type sarifOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	pattern string
+	results []sarifResult
+	// -ci keeps the secrets out of the dashboards as it does out of the logs
+	redact bool
+}
+
+func newSARIFOutput(w io.Writer) *sarifOutput {
+	return &sarifOutput{w: w}
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func (o *sarifOutput) Begin(info flagrep.ScanInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pattern = info.Pattern
+}
+
+func (o *sarifOutput) Match(m flagrep.Match) {
+	msg := fmt.Sprintf("Pattern %q found in plain content", m.Pattern)
+	if len(m.Decoders) > 0 {
+		msg = fmt.Sprintf("Pattern %q found after decoding with %s", m.Pattern, decoderChain(m.Decoders))
+	}
+
+	loc := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(m.File)},
+	}
+	// decoded matches have no meaningful position in the original file
+	if m.Line > 0 {
+		loc.Region = &sarifRegion{StartLine: m.Line}
+	}
+
+	fingerprint := sha256.Sum256([]byte(m.File + "\x00" + strings.Join(m.Decoders, ",") + "\x00" + m.Text))
+	text := m.Text
+	if o.redact {
+		text = redact(text)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results = append(o.results, sarifResult{
+		RuleID:    sarifRuleID,
+		Level:     "warning",
+		Message:   sarifMessage{Text: msg},
+		Locations: []sarifLocation{{PhysicalLocation: loc}},
+		PartialFingerprints: map[string]string{
+			"flagrepMatch/v1": hex.EncodeToString(fingerprint[:]),
+		},
+		Properties: map[string]any{
+			"decoders":   m.Decoders,
+			"match":      text,
+			"offset":     m.Offset,
+			"confidence": m.Confidence,
+		},
+	})
+}
+
+func (o *sarifOutput) Truncated(file string, decoders []string) {}
+
+func (o *sarifOutput) End(summary flagrep.ScanSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	results := o.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "flagrep",
+				Version:        version,
+				InformationURI: "https://github.com/omertheroot/flagrep",
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					Name:             "EncodedMatch",
+					ShortDescription: sarifMessage{Text: "Pattern found in plain or encoded content"},
+					FullDescription:  sarifMessage{Text: "flagrep found the search pattern, either directly or after applying a chain of decoders such as Base64, hex or ROT13."},
+				}},
+			}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	enc.Encode(log)
+}
@@ -0,0 +1,561 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// job states
+const (
+	jobRunning   = "running"
+	jobDone      = "done"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+)
+
+// runServe implements "flagrep serve": an HTTP daemon that runs scan jobs
+// submitted as JSON and keeps their results until they are deleted.
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "127.0.0.1:8080", "Address to serve the API on; give a host or :8080 to accept other machines")
+	token := fs.String("token", os.Getenv("FLAGREP_TOKEN"), "Require \"Authorization: Bearer TOKEN\" on every request (default $FLAGREP_TOKEN)")
+	root := fs.String("root", "", "Directory submitted paths are resolved in; without it only uploaded content is scanned")
+	workers := fs.Int("workers", 0, "Concurrency limit of each scan (default: scaled to the CPUs)")
+	maxDepth := fs.Int("max-depth", 4, "Highest decoder depth a job may ask for")
+	maxUpload := fs.Int64("max-upload", 32<<20, "Largest request body in bytes")
+	maxJobs := fs.Int("max-jobs", 100, "Most scans kept at once; new ones are refused while that many are running")
+	keep := fs.Duration("keep", time.Hour, "How long finished scans and their results are kept")
+	verbose := fs.Bool("v", false, "Log requests and scan diagnostics on stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep serve [-listen ADDR] [-root DIR] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	verbosity := 0
+	if *verbose {
+		verbosity = 1
+	}
+	logger, _ := newLogger(stderr, verbosity, "text")
+
+	srv := newServer(*root, logger)
+	srv.workers = *workers
+	srv.maxDepth = *maxDepth
+	srv.maxUpload = *maxUpload
+	srv.maxJobs = *maxJobs
+	srv.keep = *keep
+	srv.token = *token
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stdout, "flagrep %s listening on %s\n", version, *listen)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	srv.cancelAll()
+	return 0
+}
+
+// server holds the scan jobs of a "flagrep serve" daemon.
+type server struct {
+	root      string
+	workers   int
+	maxDepth  int
+	maxUpload int64
+	// finished jobs are forgotten after keep, or sooner when there are
+	// more than maxJobs
+	maxJobs int
+	keep    time.Duration
+	// bearer token every request must carry, "" for none
+	token  string
+	logger *slog.Logger
+	// shared by the Searchers of every job
+	searchMetrics *flagrep.Metrics
+	serveMetrics  *serveMetrics
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newServer(root string, logger *slog.Logger) *server {
+	return &server{
+		root:      root,
+		maxDepth:  4,
+		maxUpload: 32 << 20,
+		maxJobs:   100,
+		keep:      time.Hour,
+		logger:    logger,
+		jobs:      make(map[string]*job),
+
+		searchMetrics: flagrep.NewMetrics(),
+		serveMetrics:  newServeMetrics(),
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", s.submit)
+	mux.HandleFunc("GET /scans", s.list)
+	mux.HandleFunc("GET /scans/{id}", s.status)
+	mux.HandleFunc("GET /scans/{id}/results", s.results)
+	mux.HandleFunc("DELETE /scans/{id}", s.remove)
+	mux.HandleFunc("GET /metrics", s.metrics)
+	if s.token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			httpError(w, http.StatusUnauthorized, "missing or wrong token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// scanRequest is the body of POST /scans. Files are uploaded inline, their
+// content base64-encoded as JSON does for byte slices.
+type scanRequest struct {
+	Pattern       string         `json:"pattern"`
+	Regexp        bool           `json:"regexp"`
+	Preset        string         `json:"preset"`
+	Paths         []string       `json:"paths"`
+	Files         []uploadedFile `json:"files"`
+	Recursive     bool           `json:"recursive"`
+	IgnoreCase    bool           `json:"ignore_case"`
+	Depth         int            `json:"depth"`
+	ContextBefore *int           `json:"context_before"`
+	ContextAfter  *int           `json:"context_after"`
+	MinConfidence float64        `json:"min_confidence"`
+	Decoders      []string       `json:"decoders"`
+}
+
+type uploadedFile struct {
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
+
+// job is a submitted scan. It is the Output of its own Searcher.
+type job struct {
+	id      string
+	pattern string
+	created time.Time
+	cancel  context.CancelFunc
+	metrics *serveMetrics
+	// where uploads are written, stripped from match file names
+	dir string
+
+	mu           sync.Mutex
+	state        string
+	err          string
+	finished     time.Time
+	filesScanned int64
+	matches      []flagrep.Match
+}
+
+type jobStatus struct {
+	ID           string     `json:"id"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	Pattern      string     `json:"pattern"`
+	Created      time.Time  `json:"created"`
+	Finished     *time.Time `json:"finished,omitempty"`
+	FilesScanned int64      `json:"files_scanned"`
+	Matches      int        `json:"matches"`
+}
+
+type jobResults struct {
+	jobStatus
+	Results []jsonMatch `json:"results"`
+}
+
+func (j *job) Begin(info flagrep.ScanInfo) {}
+
+func (j *job) Match(m flagrep.Match) {
+	if j.dir != "" {
+		if rel, err := filepath.Rel(j.dir, m.File); err == nil && filepath.IsLocal(rel) {
+			m.File = rel
+		}
+	}
+	// a preset's expression is too long for a label
+	label := m.Pattern
+	if m.Preset != "" {
+		label = m.Preset
+	}
+	j.metrics.match(label)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.matches = append(j.matches, m)
+}
+
+func (j *job) Truncated(file string, decoders []string) {}
+
+func (j *job) End(summary flagrep.ScanSummary) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.filesScanned = summary.FilesScanned
+}
+
+// finish records how the scan ended.
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = time.Now()
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.state = jobCancelled
+	case err != nil:
+		j.state = jobFailed
+		j.err = err.Error()
+	default:
+		j.state = jobDone
+	}
+	j.metrics.finished(j.state, j.finished.Sub(j.created))
+}
+
+func (j *job) status() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := jobStatus{
+		ID:           j.id,
+		Status:       j.state,
+		Error:        j.err,
+		Pattern:      j.pattern,
+		Created:      j.created.UTC(),
+		FilesScanned: j.filesScanned,
+		Matches:      len(j.matches),
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished.UTC()
+		st.Finished = &finished
+	}
+	return st
+}
+
+func (s *server) submit(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxUpload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "decoding request: %v", err)
+		return
+	}
+
+	searcher, err := s.newSearcher(req)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	s.mu.Lock()
+	s.prune(1)
+	full := len(s.jobs) >= s.maxJobs
+	s.mu.Unlock()
+	if full {
+		httpError(w, http.StatusServiceUnavailable, "%d scans are kept already, delete finished ones first", s.maxJobs)
+		return
+	}
+
+	j := &job{
+		id:      newJobID(),
+		pattern: searcher.Pattern,
+		created: time.Now(),
+		state:   jobRunning,
+		metrics: s.serveMetrics,
+	}
+	if len(req.Files) > 0 {
+		j.dir, err = writeUploads(req.Files)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		searcher.Paths = append(searcher.Paths, j.dir)
+	}
+	searcher.Output = j
+	searcher.Metrics = s.searchMetrics
+	searcher.Logger = s.logger.With("scan", j.id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	s.mu.Lock()
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	s.logger.Info("scan submitted", "scan", j.id, "pattern", searcher.Pattern, "paths", len(req.Paths), "files", len(req.Files))
+	go func() {
+		defer cancel()
+		err := searcher.RunContext(ctx)
+		if j.dir != "" {
+			os.RemoveAll(j.dir)
+		}
+		j.finish(err)
+		s.logger.Info("scan finished", "scan", j.id, "err", err)
+	}()
+
+	w.Header().Set("Location", "/scans/"+j.id)
+	writeJSON(w, http.StatusAccepted, j.status())
+}
+
+// newSearcher validates a request and builds the Searcher for it, with the
+// same defaults as the command line.
+func (s *server) newSearcher(req scanRequest) (*flagrep.Searcher, error) {
+	depth := req.Depth
+	if depth == 0 {
+		depth = 2
+	}
+
+	var preset *flagrep.Preset
+	if req.Preset != "" {
+		p, err := flagrep.LookupPreset(req.Preset)
+		if err != nil {
+			return nil, err
+		}
+		preset = &p
+		req.Pattern = p.Name
+		if req.Depth == 0 {
+			depth = p.Depth
+		}
+	}
+	if req.Pattern == "" {
+		return nil, errors.New("missing pattern")
+	}
+	if depth < 0 || depth > s.maxDepth {
+		return nil, fmt.Errorf("depth must be between 0 and %d", s.maxDepth)
+	}
+	if len(req.Paths) == 0 && len(req.Files) == 0 {
+		return nil, errors.New("nothing to scan: give paths or files")
+	}
+
+	paths := make([]string, 0, len(req.Paths))
+	for _, p := range req.Paths {
+		if s.root == "" {
+			return nil, errors.New("scanning paths is disabled, upload files instead")
+		}
+		// stdin and anything outside the root are off limits, also through
+		// symbolic links
+		if !filepath.IsLocal(p) || !flagrep.InsideRoot(s.root, filepath.Join(s.root, p)) {
+			return nil, fmt.Errorf("path %q is not inside the server root", p)
+		}
+		paths = append(paths, filepath.Join(s.root, p))
+	}
+
+	before, after := defaultContextBefore, defaultContextAfter
+	if req.ContextBefore != nil || req.ContextAfter != nil {
+		before, after = 0, 0
+		if req.ContextBefore != nil {
+			before = *req.ContextBefore
+		}
+		if req.ContextAfter != nil {
+			after = *req.ContextAfter
+		}
+	}
+
+	searcher := flagrep.NewSearcher(paths, req.Pattern, req.Recursive, !req.IgnoreCase, s.workers, depth, before, after, false)
+	switch {
+	case preset != nil:
+		if err := searcher.UseRegexp(preset.Pattern); err != nil {
+			return nil, err
+		}
+		searcher.Preset = preset.Name
+		if preset.Decoders != nil && req.Decoders == nil {
+			req.Decoders = preset.Decoders
+		}
+	case req.Regexp:
+		if err := searcher.UseRegexp(req.Pattern); err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+	}
+	if req.Decoders != nil {
+		all := flagrep.Decoders()
+		searcher.Decoders = make(map[string]flagrep.DecoderFunc)
+		for _, name := range req.Decoders {
+			d, ok := all[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown decoder %q", name)
+			}
+			searcher.Decoders[name] = d
+		}
+	}
+	searcher.MinConfidence = req.MinConfidence
+	searcher.Root = s.root
+	return searcher, nil
+}
+
+// writeUploads stores uploaded files in a new temporary directory.
+func writeUploads(files []uploadedFile) (string, error) {
+	dir, err := os.MkdirTemp("", "flagrep-serve-")
+	if err != nil {
+		return "", err
+	}
+	for i, f := range files {
+		name := f.Name
+		if name == "" {
+			name = "upload-" + strconv.Itoa(i+1)
+		}
+		// flat, so the directory is scanned without recursing
+		if !filepath.IsLocal(name) || filepath.Base(name) != name {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("invalid file name %q", f.Name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), f.Content, 0o600); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// prune forgets finished jobs kept longer than s.keep, then the oldest
+// finished ones until room more jobs fit in s.maxJobs. s.mu must be held.
+func (s *server) prune(room int) {
+	var finished []*job
+	for id, j := range s.jobs {
+		st := j.status()
+		if st.Finished == nil {
+			continue
+		}
+		if time.Since(*st.Finished) > s.keep {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, j)
+	}
+	slices.SortFunc(finished, func(a, b *job) int { return a.created.Compare(b.created) })
+	for _, j := range finished {
+		if len(s.jobs)+room <= s.maxJobs {
+			break
+		}
+		delete(s.jobs, j.id)
+	}
+}
+
+func (s *server) lookup(w http.ResponseWriter, r *http.Request) *job {
+	s.mu.Lock()
+	s.prune(0)
+	j := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if j == nil {
+		httpError(w, http.StatusNotFound, "no scan %q", r.PathValue("id"))
+	}
+	return j
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.prune(0)
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(jobs, func(a, b *job) int { return a.created.Compare(b.created) })
+	statuses := make([]jobStatus, len(jobs))
+	for i, j := range jobs {
+		statuses[i] = j.status()
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		writeJSON(w, http.StatusOK, j.status())
+	}
+}
+
+// results returns the matches found so far, all of them once the job is
+// no longer running.
+func (s *server) results(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+	res := jobResults{jobStatus: j.status()}
+	j.mu.Lock()
+	res.Results = make([]jsonMatch, len(j.matches))
+	for i, m := range j.matches {
+		res.Results[i] = newJSONMatch(m)
+	}
+	j.mu.Unlock()
+	writeJSON(w, http.StatusOK, res)
+}
+
+// remove cancels a running job, or forgets a finished one.
+func (s *server) remove(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+	if j.status().Status == jobRunning {
+		j.cancel()
+		writeJSON(w, http.StatusAccepted, j.status())
+		return
+	}
+	s.mu.Lock()
+	delete(s.jobs, j.id)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelAll stops every running job, on shutdown.
+func (s *server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+}
+
+func newJobID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, code int, format string, args ...any) {
+	writeJSON(w, code, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
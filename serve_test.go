@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	root := t.TempDir()
+	secret := base64.StdEncoding.EncodeToString([]byte("the flag{served} is here"))
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte(secret), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newServer(root, slog.New(slog.DiscardHandler)).handler())
+	defer ts.Close()
+
+	post := func(body string) (*http.Response, jobStatus) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/scans", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var st jobStatus
+		json.NewDecoder(resp.Body).Decode(&st)
+		return resp, st
+	}
+	wait := func(id string) jobResults {
+		t.Helper()
+		for range 100 {
+			resp, err := http.Get(ts.URL + "/scans/" + id + "/results")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var res jobResults
+			json.NewDecoder(resp.Body).Decode(&res)
+			resp.Body.Close()
+			if res.Status != jobRunning {
+				return res
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("scan %s did not finish", id)
+		return jobResults{}
+	}
+
+	resp, st := post(`{"pattern": "flag{", "paths": ["a.txt"], "depth": 1}`)
+	if resp.StatusCode != http.StatusAccepted || st.ID == "" {
+		t.Fatalf("submit: %s %+v", resp.Status, st)
+	}
+	res := wait(st.ID)
+	if res.Status != jobDone || len(res.Results) != 1 || res.Results[0].Match != "flag{" || res.FilesScanned != 1 {
+		t.Errorf("path scan: %+v", res)
+	}
+
+	upload, _ := json.Marshal(scanRequest{
+		Pattern:    "FLAG{",
+		IgnoreCase: true,
+		Files:      []uploadedFile{{Name: "dump.bin", Content: []byte(secret)}},
+	})
+	_, st = post(string(upload))
+	res = wait(st.ID)
+	if len(res.Results) == 0 || res.Results[0].File != "dump.bin" {
+		t.Errorf("upload scan: %+v", res)
+	}
+
+	for _, body := range []string{
+		`{"pattern": "x", "paths": ["../etc/passwd"]}`,
+		`{"pattern": "x", "paths": ["/etc/passwd"]}`,
+		`{"pattern": "x"}`,
+		`{"pattern": "x", "paths": ["a.txt"], "depth": 9}`,
+		`{"pattern": "x", "files": [{"name": "../x", "content": ""}]}`,
+		`{"pattern": "x", "bogus": 1}`,
+	} {
+		if resp, _ := post(body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got %s, want 400", body, resp.Status)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/scans/"+st.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: %s", resp.Status)
+	}
+	resp, err = http.Get(ts.URL + "/scans/" + st.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted scan: %s", resp.Status)
+	}
+}
+
+func TestServeWithoutRoot(t *testing.T) {
+	ts := httptest.NewServer(newServer("", slog.New(slog.DiscardHandler)).handler())
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/scans", "application/json", bytes.NewBufferString(`{"pattern": "x", "paths": ["."]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %s, want 400", resp.Status)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	srv := newServer("", slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+
+	upload, _ := json.Marshal(scanRequest{
+		Pattern: "flag{",
+		Depth:   1,
+		Files:   []uploadedFile{{Name: "a", Content: []byte(base64.StdEncoding.EncodeToString([]byte("flag{m}")))}},
+	})
+	resp, err := http.Post(ts.URL+"/scans", "application/json", bytes.NewReader(upload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var body string
+	for range 100 {
+		resp, err := http.Get(ts.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		body = string(b)
+		if strings.Contains(body, `flagrep_scans_total{status="done"} 1`) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, want := range []string{
+		"flagrep_files_scanned_total 1\n",
+		"flagrep_bytes_scanned_total 12\n",
+		`flagrep_decode_attempts_total{decoder="base64"} 1` + "\n",
+		`flagrep_matches_total{pattern="flag{"} 1` + "\n",
+		"flagrep_scans_running 0\n",
+		`flagrep_scan_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		"# TYPE flagrep_scan_duration_seconds histogram\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestServeSymlinks(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("flag{outside}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(root, "dir"), 0o755)
+	os.WriteFile(filepath.Join(root, "dir", "inside.txt"), []byte("flag{inside}"), 0o644)
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "dir", "leak.txt")); err != nil {
+		t.Skip(err)
+	}
+	os.Symlink(outside, filepath.Join(root, "out"))
+	os.Symlink("inside.txt", filepath.Join(root, "dir", "alias.txt"))
+
+	srv := newServer(root, slog.New(slog.DiscardHandler))
+	if _, err := srv.newSearcher(scanRequest{Pattern: "flag{", Paths: []string{"out/secret.txt"}}); err == nil {
+		t.Error("accepted a path through a symbolic link out of the root")
+	}
+	searcher, err := srv.newSearcher(scanRequest{Pattern: "flag{", Paths: []string{"dir"}, Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &job{metrics: srv.serveMetrics}
+	searcher.Output = j
+	if err := searcher.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range j.matches {
+		if strings.Contains(m.File, "leak") {
+			t.Errorf("followed a link out of the root: %+v", m)
+		}
+	}
+	if len(j.matches) != 2 {
+		t.Errorf("expected matches in inside.txt and alias.txt, got %+v", j.matches)
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	srv := newServer("", slog.New(slog.DiscardHandler))
+	srv.token = "s3cret"
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+
+	for auth, want := range map[string]int{"": http.StatusUnauthorized, "Bearer nope": http.StatusUnauthorized, "Bearer s3cret": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/scans", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%q: got %s, want %d", auth, resp.Status, want)
+		}
+	}
+}
+
+func TestServePrune(t *testing.T) {
+	srv := newServer("", slog.New(slog.DiscardHandler))
+	srv.maxJobs = 2
+	now := time.Now()
+	for i, finished := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute), {}} {
+		j := &job{id: string(rune('a' + i)), created: now.Add(time.Duration(i) * time.Second), state: jobDone, finished: finished}
+		if finished.IsZero() {
+			j.state = jobRunning
+		}
+		srv.jobs[j.id] = j
+	}
+
+	srv.prune(0)
+	if _, ok := srv.jobs["a"]; ok || len(srv.jobs) != 2 {
+		t.Errorf("expired scan kept: %v", srv.jobs)
+	}
+	// room for one more: the finished scan goes, the running one stays
+	srv.prune(1)
+	if _, ok := srv.jobs["c"]; !ok || len(srv.jobs) != 1 {
+		t.Errorf("got %v", srv.jobs)
+	}
+	srv.prune(2)
+	if len(srv.jobs) != 1 {
+		t.Errorf("running scan dropped: %v", srv.jobs)
+	}
+}
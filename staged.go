@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// stagedFiles lists the files added, copied, modified or renamed in the git
+// index, relative to the working directory. Deleted files have nothing to
+// scan.
+func stagedFiles() ([]string, error) {
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git("diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// git reports the top with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+
+	var files []string
+	for _, name := range splitNUL(out) {
+		// git prints paths relative to the top of the work tree
+		path := filepath.Join(strings.TrimSpace(top), filepath.FromSlash(name))
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return string(out), nil
+}
+
+// readFiles0 reads NUL-separated file names, as written by find -print0 and
+// git -z, from name or from stdin if name is "-".
+func readFiles0(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return splitNUL(string(data)), nil
+}
+
+func splitNUL(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
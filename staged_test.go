@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStagedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	for _, name := range []string{"a.txt", "sub/b c.txt", "unstaged.txt"} {
+		os.MkdirAll(filepath.Dir(name), 0o755)
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("add", "a.txt", "sub/b c.txt")
+
+	files, err := stagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	if want := []string{"a.txt", filepath.Join("sub", "b c.txt")}; !slices.Equal(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+
+	// relative to the working directory, like every other path
+	t.Chdir("sub")
+	files, err = stagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	if want := []string{filepath.Join("..", "a.txt"), "b c.txt"}; !slices.Equal(files, want) {
+		t.Errorf("from sub: got %q, want %q", files, want)
+	}
+}
+
+func TestReadFiles0(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list")
+	if err := os.WriteFile(path, []byte("a\x00b c\x00\x00new\nline\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := readFiles0(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b c", "new\nline"}; !slices.Equal(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// statsOutput prints the throughput of every worker once the scan ends,
+// for -stats.
+type statsOutput struct {
+	w io.Writer
+}
+
+func (o statsOutput) Begin(info flagrep.ScanInfo)              {}
+func (o statsOutput) Match(m flagrep.Match)                    {}
+func (o statsOutput) Truncated(file string, decoders []string) {}
+
+func (o statsOutput) End(summary flagrep.ScanSummary) {
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "worker\tfiles\tbytes\tbusy\tMB/s\t")
+	var files, bytes int64
+	for _, w := range summary.Workers {
+		files += w.Files
+		bytes += w.Bytes
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\t\n", w.ID, w.Files, w.Bytes, w.Busy.Round(time.Millisecond), throughput(w.Bytes, w.Busy))
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\t%s\t%s\t\n", files, bytes, summary.Duration.Round(time.Millisecond), throughput(bytes, summary.Duration))
+	tw.Flush()
+}
+
+func throughput(bytes int64, d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", float64(bytes)/1e6/d.Seconds())
+}
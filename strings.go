@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/omertheroot/flagrep/pkg/flagrep"
+)
+
+// runStrings implements "flagrep strings FILE...".
+func runStrings(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("strings", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	minLen := fs.Int("n", 4, "Minimum string length")
+	encodings := fs.String("encoding", "ascii,utf16le", "Comma separated encodings to extract: "+strings.Join(slices.Sorted(maps.Keys(flagrep.StringExtractors)), ", "))
+	radix := fs.String("t", "", "Print the offset of each string: x (hex) or d (decimal)")
+	decode := fs.Bool("decode", false, "Also print the readable results of running each string through the decoders")
+	depth := fs.Int("depth", 1, "Decoder combination depth for -decode")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: flagrep strings [options] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var extractors []func([]byte, int) []flagrep.ExtractedString
+	for _, name := range strings.Split(*encodings, ",") {
+		extractor, ok := flagrep.StringExtractors[strings.TrimSpace(name)]
+		if !ok {
+			fmt.Fprintf(stderr, "Error: unknown encoding %q\n", name)
+			return 2
+		}
+		extractors = append(extractors, extractor)
+	}
+	var offsetFormat string
+	switch *radix {
+	case "":
+	case "x":
+		offsetFormat = "%8x "
+	case "d":
+		offsetFormat = "%8d "
+	default:
+		fmt.Fprintf(stderr, "Error: invalid -t value %q (want x or d)\n", *radix)
+		return 2
+	}
+
+	decoders := flagrep.Decoders()
+	status := 0
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			status = 1
+			continue
+		}
+
+		var found []flagrep.ExtractedString
+		for _, extract := range extractors {
+			found = append(found, extract(data, *minLen)...)
+		}
+		slices.SortStableFunc(found, func(a, b flagrep.ExtractedString) int { return a.Offset - b.Offset })
+
+		for _, s := range found {
+			if fs.NArg() > 1 {
+				fmt.Fprintf(stdout, "%s: ", path)
+			}
+			if offsetFormat != "" {
+				fmt.Fprintf(stdout, offsetFormat, s.Offset)
+			}
+			if len(extractors) > 1 && s.Encoding != "ascii" {
+				fmt.Fprintf(stdout, "[%s] ", s.Encoding)
+			}
+			fmt.Fprintln(stdout, s.Text)
+
+			if !*decode {
+				continue
+			}
+			for _, v := range flagrep.DecodeVariants(s.Text, decoders, *depth) {
+				fmt.Fprintf(stdout, "    %s: %s\n", decoderChain(v.Decoders), escapeControl(v.Text))
+			}
+		}
+	}
+	return status
+}